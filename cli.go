@@ -0,0 +1,654 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/DelineaXPM/terraform-provider-tss/v3/delinea"
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"golang.org/x/term"
+)
+
+// Exit codes of the state encryption commands
+const (
+	exitOK      = 0 // Every file was processed
+	exitFailure = 1 // At least one file could not be processed
+	exitUsage   = 2 // The command line, the passphrase or the key settings are invalid
+)
+
+// stdioName stands for stdin and stdout in place of a file name
+const stdioName = "-"
+
+const usage = `Usage: terraform-provider-tss <command> [flags] [file...]
+
+Encrypts and decrypts Terraform state files. Run without arguments, the binary
+serves the provider to Terraform.
+
+Commands:
+  encrypt   Encrypt plaintext state files in place
+  decrypt   Decrypt encrypted state files in place
+  status    Show whether state files are encrypted, and how
+  rekey     Encrypt state files again with a new passphrase or key
+  verify    Check that state files decrypt with the passphrase, without writing them
+
+Files are changed in place: the new content is written to a temporary file
+that replaces the original, which keeps its permissions. With --backup, the
+previous content is kept in <file>.bak until the new file is verified. Without
+files, or with "-", the state is read from stdin and the result is written to
+stdout. Files that do not exist are skipped by encrypt and decrypt, and encrypt
+and decrypt leave files that are already in the wanted form unchanged.
+
+The passphrase is read from the file given with --passphrase-file, from the
+TFSTATE_PASSPHRASE environment variable, or from a prompt on the terminal.
+Rekey reads the new passphrase from --new-passphrase-file,
+TFSTATE_NEW_PASSPHRASE or a prompt.
+
+Instead of a passphrase, the commands can use a random key stored base64
+encoded in a Secret Server secret, given with --key-secret-id and --key-field or
+TFSTATE_KEY_SECRET_ID and TFSTATE_KEY_FIELD. Rekey takes the new key from
+--new-key-secret-id and --new-key-field or TFSTATE_NEW_KEY_SECRET_ID and
+TFSTATE_NEW_KEY_FIELD, so it also moves files from a passphrase to a key and
+back. The secret is read with the credentials in TSS_SERVER_URL, TSS_USERNAME,
+TSS_PASSWORD, TSS_DOMAIN and TSS_TOKEN, as used by the provider.
+
+Exit codes: 0 on success, 1 if a file could not be processed, 2 on usage errors
+or if the passphrase or the key cannot be read.
+
+Run 'terraform-provider-tss <command> --help' for the flags of a command.
+`
+
+// cli runs the state encryption commands. Its fields are replaced in tests.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+
+	// prompt reads a passphrase without echoing it, or fails if there is no terminal
+	prompt func(message string) (string, error)
+
+	// secretField reads a field of a Secret Server secret
+	secretField func(id int, field string) (string, error)
+}
+
+// newCLI returns a cli on the standard streams and the process environment
+func newCLI() *cli {
+	c := &cli{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		getenv: os.Getenv,
+		prompt: promptTerminal,
+	}
+	c.secretField = c.readSecretField
+	return c
+}
+
+// command is a subcommand with its flags
+type command struct {
+	summary string
+	run     func(c *cli, flags *commandFlags, files []string) int
+}
+
+// commandFlags are the flags of the subcommands; each command registers the ones it uses
+type commandFlags struct {
+	passphraseFile    string
+	newPassphraseFile string
+	kdf               string
+	backup            bool
+	keySecretID       int
+	keyField          string
+	newKeySecretID    int
+	newKeyField       string
+}
+
+var commands = map[string]command{
+	"encrypt": {summary: "Encrypt plaintext state files in place", run: (*cli).encrypt},
+	"decrypt": {summary: "Decrypt encrypted state files in place", run: (*cli).decrypt},
+	"status":  {summary: "Show whether state files are encrypted, and how", run: (*cli).status},
+	"rekey":   {summary: "Encrypt state files again with a new passphrase or key", run: (*cli).rekey},
+	"verify":  {summary: "Check that state files decrypt with the passphrase", run: (*cli).verify},
+}
+
+// run runs the command in args, which do not include the program name, and returns
+// the exit code
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(c.stderr, usage)
+		return exitUsage
+	}
+
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(c.stdout, usage)
+		return exitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(c.stderr, "Unknown command '%s'.\n\n%s", name, usage)
+		return exitUsage
+	}
+
+	var flags commandFlags
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: terraform-provider-tss %s [flags] [file...]\n\n%s.\n", name, cmd.summary)
+		if hasFlags(fs) {
+			fmt.Fprintln(fs.Output(), "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	if name != "status" {
+		fs.StringVar(&flags.passphraseFile, "passphrase-file", "", "read the passphrase from this `file` instead of TFSTATE_PASSPHRASE")
+	}
+	if name == "encrypt" || name == "rekey" {
+		fs.StringVar(&flags.kdf, "kdf", "", "key derivation `function` and parameters, e.g. argon2id:t=3,m=65536,p=4 (default TFSTATE_KDF or argon2id)")
+	}
+	if name != "status" {
+		fs.IntVar(&flags.keySecretID, "key-secret-id", 0, "use the key stored in the secret with this `id` instead of a passphrase (default TFSTATE_KEY_SECRET_ID)")
+		fs.StringVar(&flags.keyField, "key-field", "", "the `field` of the key secret that holds the base64 encoded key (default TFSTATE_KEY_FIELD)")
+	}
+	if name == "encrypt" || name == "decrypt" || name == "rekey" {
+		fs.BoolVar(&flags.backup, "backup", false, "keep the previous content of each file in <file>.bak until the new content is written and verified")
+	}
+	if name == "rekey" {
+		fs.StringVar(&flags.newPassphraseFile, "new-passphrase-file", "", "read the new passphrase from this `file` instead of TFSTATE_NEW_PASSPHRASE")
+		fs.IntVar(&flags.newKeySecretID, "new-key-secret-id", 0, "encrypt with the key stored in the secret with this `id` instead of a new passphrase (default TFSTATE_NEW_KEY_SECRET_ID)")
+		fs.StringVar(&flags.newKeyField, "new-key-field", "", "the `field` of the new key secret that holds the base64 encoded key (default TFSTATE_NEW_KEY_FIELD)")
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{stdioName}
+	}
+	if count(files, stdioName) > 1 {
+		fmt.Fprintln(c.stderr, "Error: stdin can only be read once.")
+		return exitUsage
+	}
+
+	return cmd.run(c, &flags, files)
+}
+
+func (c *cli) encrypt(flags *commandFlags, files []string) int {
+	kdf, err := c.kdf(flags.kdf)
+	if err != nil {
+		return c.usageError(err)
+	}
+	source := currentKey(flags)
+	source.kdf = flags.kdf
+	passphrase, fromSecret, err := c.key(source, true, files)
+	if err != nil {
+		return c.usageError(err)
+	}
+	if fromSecret {
+		kdf = delinea.KeyKDF
+	}
+
+	return c.each(files, func(name string) error {
+		if name == stdioName {
+			return c.filter(func(r *bufio.Reader, w io.Writer) error {
+				format, _, err := delinea.PeekFormat(r)
+				if err != nil {
+					return err
+				}
+				if format != delinea.FormatPlaintext {
+					_, err := io.Copy(w, r)
+					return err
+				}
+				ew, err := delinea.NewEncryptWriter(w, passphrase, kdf)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(ew, r); err != nil {
+					return err
+				}
+				return ew.Close()
+			})
+		}
+		return delinea.EncryptFileWithOptions(passphrase, name, delinea.FileOptions{KDF: kdf, Backup: flags.backup})
+	})
+}
+
+func (c *cli) decrypt(flags *commandFlags, files []string) int {
+	passphrase, _, err := c.key(currentKey(flags), false, files)
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	return c.each(files, func(name string) error {
+		if name == stdioName {
+			return c.filter(func(r *bufio.Reader, w io.Writer) error {
+				format, _, err := delinea.PeekFormat(r)
+				if err != nil {
+					return err
+				}
+				if format == delinea.FormatPlaintext {
+					_, err := io.Copy(w, r)
+					return err
+				}
+				decrypted, err := delinea.NewDecryptReader(r, passphrase)
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(w, decrypted)
+				return err
+			})
+		}
+		return delinea.DecryptFileWithOptions(passphrase, name, delinea.FileOptions{Backup: flags.backup})
+	})
+}
+
+func (c *cli) status(_ *commandFlags, files []string) int {
+	return c.each(files, func(name string) error {
+		r, closer, err := c.open(name)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+
+		format, start, err := delinea.PeekFormat(r)
+		if err != nil {
+			return err
+		}
+		if format == delinea.FormatPlaintext {
+			fmt.Fprintf(c.stdout, "%s: %s\n", name, format)
+			return nil
+		}
+
+		kdf, err := delinea.StateKDF(start)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s: %s, %s\n", name, format, kdf)
+		return nil
+	})
+}
+
+func (c *cli) rekey(flags *commandFlags, files []string) int {
+	kdf, err := c.kdf(flags.kdf)
+	if err != nil {
+		return c.usageError(err)
+	}
+	current := currentKey(flags)
+	current.prompt = "Current passphrase"
+	passphrase, _, err := c.key(current, false, files)
+	if err != nil {
+		return c.usageError(err)
+	}
+	next := newKey(flags)
+	next.kdf = flags.kdf
+	newPassphrase, fromSecret, err := c.key(next, true, files)
+	if err != nil {
+		return c.usageError(err)
+	}
+	if fromSecret {
+		kdf = delinea.KeyKDF
+	}
+
+	return c.each(files, func(name string) error {
+		if name == stdioName {
+			return c.filter(func(r *bufio.Reader, w io.Writer) error {
+				format, _, err := delinea.PeekFormat(r)
+				if err != nil {
+					return err
+				}
+				if format == delinea.FormatPlaintext {
+					return errors.New("the file is not encrypted")
+				}
+				decrypted, err := delinea.NewDecryptReader(r, passphrase)
+				if err != nil {
+					return err
+				}
+				ew, err := delinea.NewEncryptWriter(w, newPassphrase, kdf)
+				if err != nil {
+					return err
+				}
+				if _, err := io.Copy(ew, decrypted); err != nil {
+					return err
+				}
+				return ew.Close()
+			})
+		}
+		return delinea.RekeyFile(passphrase, newPassphrase, name, delinea.FileOptions{KDF: kdf, Backup: flags.backup})
+	})
+}
+
+func (c *cli) verify(flags *commandFlags, files []string) int {
+	passphrase, _, err := c.key(currentKey(flags), false, files)
+	if err != nil {
+		return c.usageError(err)
+	}
+
+	return c.each(files, func(name string) error {
+		r, closer, err := c.open(name)
+		if err != nil {
+			return err
+		}
+		defer closer.Close()
+
+		format, _, err := delinea.PeekFormat(r)
+		if err != nil {
+			return err
+		}
+		if format == delinea.FormatPlaintext {
+			return errors.New("the file is not encrypted")
+		}
+		decrypted, err := delinea.NewDecryptReader(r, passphrase)
+		if err != nil {
+			return err
+		}
+		if err := validJSON(decrypted); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "%s: OK\n", name)
+		return nil
+	})
+}
+
+// each calls fn for every file, reports the files that were skipped and the errors,
+// and returns the exit code
+func (c *cli) each(files []string, fn func(name string) error) int {
+	code := exitOK
+	for _, name := range files {
+		switch err := fn(name); {
+		case errors.Is(err, delinea.ErrAlreadyEncrypted):
+			fmt.Fprintf(c.stdout, "%s: already encrypted\n", name)
+		case errors.Is(err, delinea.ErrNotEncrypted):
+			fmt.Fprintf(c.stdout, "%s: not encrypted\n", name)
+		case err != nil:
+			fmt.Fprintf(c.stderr, "Error: %s: %s\n", name, err)
+			code = exitFailure
+		}
+	}
+	return code
+}
+
+// filter reads the state from stdin, converts it and writes it to stdout. The state is
+// converted as it is read, so stdout may hold part of the result when it fails.
+func (c *cli) filter(convert func(r *bufio.Reader, w io.Writer) error) error {
+	w := bufio.NewWriter(c.stdout)
+	if err := convert(bufio.NewReader(c.stdin), w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write stdout: %v", err)
+	}
+	return nil
+}
+
+// open returns a reader of a file, or of stdin for "-", and the closer of the file
+func (c *cli) open(name string) (*bufio.Reader, io.Closer, error) {
+	if name == stdioName {
+		return bufio.NewReader(c.stdin), io.NopCloser(nil), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bufio.NewReader(f), f, nil
+}
+
+// validJSON checks that r holds one JSON value, without reading it into memory
+func validJSON(r io.Reader) error {
+	invalid := errors.New("the file decrypts, but not to JSON state")
+
+	dec := json.NewDecoder(r)
+	depth, values := 0, 0
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return invalid
+			}
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			values++
+		}
+	}
+
+	if depth != 0 || values != 1 {
+		return invalid
+	}
+	return nil
+}
+
+// kdf returns the key derivation function of the --kdf flag or of TFSTATE_KDF
+func (c *cli) kdf(spec string) (delinea.KDFParams, error) {
+	if spec == "" {
+		spec = c.getenv("TFSTATE_KDF")
+	}
+	if spec == "" {
+		return delinea.DefaultKDF, nil
+	}
+	return delinea.ParseKDF(spec)
+}
+
+// keySource names the flags and environment variables that select a key: a
+// passphrase, or a key stored in a Secret Server secret
+type keySource struct {
+	secretID       int
+	field          string
+	passphraseFile string
+	kdf            string // the --kdf flag, which only applies to passphrases
+
+	idFlag, fieldFlag, fileFlag    string
+	idEnv, fieldEnv, passphraseEnv string
+	prompt                         string
+}
+
+// currentKey is the key the files are encrypted with
+func currentKey(flags *commandFlags) keySource {
+	return keySource{
+		secretID:       flags.keySecretID,
+		field:          flags.keyField,
+		passphraseFile: flags.passphraseFile,
+		idFlag:         "--key-secret-id",
+		fieldFlag:      "--key-field",
+		fileFlag:       "--passphrase-file",
+		idEnv:          "TFSTATE_KEY_SECRET_ID",
+		fieldEnv:       "TFSTATE_KEY_FIELD",
+		passphraseEnv:  "TFSTATE_PASSPHRASE",
+		prompt:         "Passphrase",
+	}
+}
+
+// newKey is the key rekey encrypts the files with
+func newKey(flags *commandFlags) keySource {
+	return keySource{
+		secretID:       flags.newKeySecretID,
+		field:          flags.newKeyField,
+		passphraseFile: flags.newPassphraseFile,
+		idFlag:         "--new-key-secret-id",
+		fieldFlag:      "--new-key-field",
+		fileFlag:       "--new-passphrase-file",
+		idEnv:          "TFSTATE_NEW_KEY_SECRET_ID",
+		fieldEnv:       "TFSTATE_NEW_KEY_FIELD",
+		passphraseEnv:  "TFSTATE_NEW_PASSPHRASE",
+		prompt:         "New passphrase",
+	}
+}
+
+// key returns the key of src: the key from the secret of its ID flag or environment
+// variable if one is set, and the passphrase otherwise. fromSecret reports whether
+// it is a key from Secret Server.
+func (c *cli) key(src keySource, confirm bool, files []string) (key string, fromSecret bool, err error) {
+	id, field := src.secretID, src.field
+	if id == 0 && field == "" {
+		if env := c.getenv(src.idEnv); env != "" {
+			if id, err = strconv.Atoi(env); err != nil || id <= 0 {
+				return "", false, fmt.Errorf("%s '%s' is not a secret ID", src.idEnv, env)
+			}
+			field = c.getenv(src.fieldEnv)
+		}
+	}
+
+	switch {
+	case id == 0 && field == "":
+		passphrase, err := c.passphrase(src.passphraseFile, src.passphraseEnv, src.prompt, confirm, files)
+		return passphrase, false, err
+	case id <= 0:
+		return "", false, fmt.Errorf("%s needs %s", src.fieldFlag, src.idFlag)
+	case field == "":
+		return "", false, fmt.Errorf("set the field that holds the key with %s or %s", src.fieldFlag, src.fieldEnv)
+	case src.passphraseFile != "":
+		return "", false, fmt.Errorf("%s cannot be used with a key from Secret Server", src.fileFlag)
+	case src.kdf != "":
+		return "", false, errors.New("--kdf cannot be used with a key from Secret Server")
+	}
+
+	value, err := c.secretField(id, field)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the key from secret %d: %v", id, err)
+	}
+	stateKey, err := delinea.ParseStateKey(value)
+	if err != nil {
+		return "", false, fmt.Errorf("the field '%s' of secret %d does not hold a key: %v", field, id, err)
+	}
+	return string(stateKey), true, nil
+}
+
+// readSecretField reads a field of a secret with the credentials of the TSS_*
+// environment variables, like the provider
+func (c *cli) readSecretField(id int, field string) (string, error) {
+	client, err := delinea.NewClient(server.Configuration{
+		ServerURL: c.getenv("TSS_SERVER_URL"),
+		Credentials: server.UserCredential{
+			Username: c.getenv("TSS_USERNAME"),
+			Password: c.getenv("TSS_PASSWORD"),
+			Token:    c.getenv("TSS_TOKEN"),
+			Domain:   c.getenv("TSS_DOMAIN"),
+		},
+	}, delinea.ClientOptions{})
+	if err != nil {
+		return "", fmt.Errorf("%v; set TSS_SERVER_URL with TSS_USERNAME and TSS_PASSWORD, or with TSS_TOKEN", err)
+	}
+
+	// The SDK logs every request, which Terraform filters for the provider but which
+	// would mix with the output of the commands
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	secret, err := client.Secret(context.Background(), id)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.Field(field)
+	if !ok {
+		return "", fmt.Errorf("the secret does not contain the field '%s'", field)
+	}
+	return value, nil
+}
+
+// passphrase returns the passphrase from the given file, the environment variable
+// or a prompt, in this order. The prompt is only used when stdin does not carry the
+// state, and asks twice when confirm is set.
+func (c *cli) passphrase(file, envVar, name string, confirm bool, files []string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the passphrase file: %v", err)
+		}
+		passphrase := strings.TrimRight(string(data), "\r\n")
+		if passphrase == "" {
+			return "", fmt.Errorf("the passphrase file %s is empty", file)
+		}
+		return passphrase, nil
+	}
+
+	if passphrase := c.getenv(envVar); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if count(files, stdioName) > 0 {
+		return "", fmt.Errorf("set %s or use a passphrase file when the state is read from stdin", envVar)
+	}
+
+	passphrase, err := c.prompt(name + ": ")
+	if err != nil {
+		return "", fmt.Errorf("set %s, use a passphrase file or run in a terminal: %v", envVar, err)
+	}
+	if passphrase == "" {
+		return "", errors.New("the passphrase is empty")
+	}
+	if confirm {
+		again, err := c.prompt("Repeat " + strings.ToLower(name[:1]) + name[1:] + ": ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// usageError reports an error in the command line or the passphrase settings
+func (c *cli) usageError(err error) int {
+	fmt.Fprintf(c.stderr, "Error: %s\n", err)
+	return exitUsage
+}
+
+// promptTerminal reads a passphrase from the terminal without echoing it
+func promptTerminal(message string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("stdin is not a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, message)
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the passphrase: %v", err)
+	}
+	return string(passphrase), nil
+}
+
+// hasFlags reports whether any flag is defined in fs
+func hasFlags(fs *flag.FlagSet) bool {
+	found := false
+	fs.VisitAll(func(*flag.Flag) { found = true })
+	return found
+}
+
+// count returns how often value occurs in values
+func count(values []string, value string) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DelineaXPM/terraform-provider-tss/v3/delinea"
+)
+
+const testState = `{"version": 4, "resources": []}`
+
+// testKDF keeps the key derivation cheap
+const testKDF = "argon2id:t=1,m=64,p=1"
+
+// testSecretFields are the secret fields read by runCLI, keyed by secret ID and field
+var testSecretFields = map[string]string{
+	"7/key":   "Wm/3vGqMw0Dm6bC+5nY1n7eUqHCx8hE8Dh7nSiy6n0Q=\n",
+	"7/other": "c2hvcnQ=",
+	"9/key":   "Ct0x+gWT3uO2EGcvhls2QdlqVtzePeab4HZRoB+IgNE=",
+}
+
+// runCLI runs the commands with the given environment, stdin and prompt answers, and
+// returns the exit code with stdout and stderr
+func runCLI(t *testing.T, env map[string]string, stdin string, answers []string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+		getenv: func(key string) string { return env[key] },
+		prompt: func(string) (string, error) {
+			if len(answers) == 0 {
+				return "", errors.New("stdin is not a terminal")
+			}
+			answer := answers[0]
+			answers = answers[1:]
+			return answer, nil
+		},
+		secretField: func(id int, field string) (string, error) {
+			value, ok := testSecretFields[fmt.Sprintf("%d/%s", id, field)]
+			if !ok {
+				return "", errors.New("404 Not Found")
+			}
+			return value, nil
+		},
+	}
+	code := c.run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFiles writes state files with the given content into a temporary directory
+func writeFiles(t *testing.T, content string, names ...string) []string {
+	t.Helper()
+
+	dir := t.TempDir()
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(dir, name)
+		if err := os.WriteFile(paths[i], []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCLIUsage(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantCode int
+		wantOut  string
+		wantErr  string
+	}{
+		{args: nil, wantCode: exitUsage, wantErr: "Usage:"},
+		{args: []string{"--help"}, wantCode: exitOK, wantOut: "Commands:"},
+		{args: []string{"help"}, wantCode: exitOK, wantOut: "Exit codes:"},
+		{args: []string{"shred"}, wantCode: exitUsage, wantErr: "Unknown command 'shred'"},
+		{args: []string{"encrypt", "--help"}, wantCode: exitOK, wantErr: "-passphrase-file"},
+		{args: []string{"status", "--kdf", "scrypt"}, wantCode: exitUsage, wantErr: "flag provided but not defined"},
+		{args: []string{"decrypt", "-", "-"}, wantCode: exitUsage, wantErr: "stdin can only be read once"},
+		{args: []string{"encrypt", "--kdf", "md5", "state"}, wantCode: exitUsage, wantErr: "unknown key derivation function"},
+		{args: []string{"encrypt", "state"}, wantCode: exitUsage, wantErr: "set TFSTATE_PASSPHRASE"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, nil, "", nil, tt.args...)
+			if code != tt.wantCode || !strings.Contains(stdout, tt.wantOut) || !strings.Contains(stderr, tt.wantErr) {
+				t.Errorf("run() = %d, stdout %q, stderr %q; want %d with %q and %q", code, stdout, stderr, tt.wantCode, tt.wantOut, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCLIEncryptDecryptFiles(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate", "terraform.tfstate.backup")
+	env := map[string]string{"TFSTATE_PASSPHRASE": "passphrase", "TFSTATE_KDF": testKDF}
+	missing := filepath.Join(t.TempDir(), "missing.tfstate")
+
+	if code, _, stderr := runCLI(t, env, "", nil, append([]string{"encrypt", missing}, files...)...); code != exitOK {
+		t.Fatalf("encrypt = %d, %s", code, stderr)
+	}
+	for _, file := range files {
+		if format := delinea.DetectFormat([]byte(readFile(t, file))); format != delinea.FormatEncrypted {
+			t.Errorf("%s is %s after encrypt", file, format)
+		}
+	}
+
+	code, stdout, stderr := runCLI(t, env, "", nil, "encrypt", files[0])
+	if want := files[0] + ": already encrypted\n"; code != exitOK || stdout != want {
+		t.Errorf("encrypt of an encrypted file = %d, %q, %q, want %q", code, stdout, stderr, want)
+	}
+
+	code, stdout, _ = runCLI(t, nil, "", nil, "status", files[0])
+	if want := files[0] + ": encrypted, " + testKDF + "\n"; code != exitOK || stdout != want {
+		t.Errorf("status = %d, %q, want %q", code, stdout, want)
+	}
+
+	if code, _, stderr := runCLI(t, map[string]string{"TFSTATE_PASSPHRASE": "wrong"}, "", nil, "decrypt", files[0]); code != exitFailure || !strings.Contains(stderr, files[0]) {
+		t.Errorf("decrypt with the wrong passphrase = %d, %q, want a failure for the file", code, stderr)
+	}
+	if code, stdout, stderr := runCLI(t, env, "", nil, append([]string{"verify"}, files...)...); code != exitOK || strings.Count(stdout, "OK") != 2 {
+		t.Errorf("verify = %d, %q, %q", code, stdout, stderr)
+	}
+
+	if code, _, stderr := runCLI(t, env, "", nil, append([]string{"decrypt"}, files...)...); code != exitOK {
+		t.Fatalf("decrypt = %d, %s", code, stderr)
+	}
+	for _, file := range files {
+		if got := readFile(t, file); got != testState {
+			t.Errorf("%s = %q after decrypt, want the state", file, got)
+		}
+	}
+
+	code, stdout, stderr = runCLI(t, env, "", nil, "decrypt", files[0])
+	if want := files[0] + ": not encrypted\n"; code != exitOK || stdout != want {
+		t.Errorf("decrypt of a plaintext file = %d, %q, %q, want %q", code, stdout, stderr, want)
+	}
+	if code, _, stderr := runCLI(t, env, "", nil, "verify", files[0]); code != exitFailure || !strings.Contains(stderr, "not encrypted") {
+		t.Errorf("verify of a plaintext file = %d, %q", code, stderr)
+	}
+}
+
+func TestCLIStdio(t *testing.T) {
+	env := map[string]string{"TFSTATE_PASSPHRASE": "passphrase", "TFSTATE_KDF": testKDF}
+
+	code, encrypted, stderr := runCLI(t, env, testState, nil, "encrypt")
+	if code != exitOK || delinea.DetectFormat([]byte(encrypted)) != delinea.FormatEncrypted {
+		t.Fatalf("encrypt from stdin = %d, %q, %s", code, encrypted, stderr)
+	}
+
+	code, decrypted, stderr := runCLI(t, env, encrypted, nil, "decrypt", "-")
+	if code != exitOK || decrypted != testState {
+		t.Fatalf("decrypt from stdin = %d, %q, %s", code, decrypted, stderr)
+	}
+
+	// stdin carries the state, so the passphrase cannot be prompted for
+	if code, _, stderr := runCLI(t, nil, encrypted, []string{"passphrase"}, "decrypt"); code != exitUsage || !strings.Contains(stderr, "stdin") {
+		t.Errorf("decrypt from stdin without a passphrase = %d, %q", code, stderr)
+	}
+}
+
+func TestCLIPassphraseSources(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate")
+	passphraseFile := writeFiles(t, "from-file\n", "passphrase")[0]
+	env := map[string]string{"TFSTATE_KDF": testKDF}
+
+	// A prompted passphrase must be repeated when encrypting
+	if code, _, stderr := runCLI(t, env, "", []string{"prompted", "typo"}, "encrypt", files[0]); code != exitUsage || !strings.Contains(stderr, "do not match") {
+		t.Fatalf("encrypt with mismatched prompts = %d, %q", code, stderr)
+	}
+	if code, _, stderr := runCLI(t, env, "", []string{"prompted", "prompted"}, "encrypt", files[0]); code != exitOK {
+		t.Fatalf("encrypt with a prompted passphrase = %d, %q", code, stderr)
+	}
+
+	// The passphrase file takes precedence over the environment
+	env["TFSTATE_PASSPHRASE"] = "prompted"
+	env["TFSTATE_NEW_PASSPHRASE"] = "ignored"
+	code, _, stderr := runCLI(t, env, "", nil, "rekey", "--new-passphrase-file", passphraseFile, "--kdf", "scrypt:n=10", files[0])
+	if code != exitOK {
+		t.Fatalf("rekey = %d, %q", code, stderr)
+	}
+
+	if code, stdout, _ := runCLI(t, nil, "", nil, "status", files[0]); !strings.Contains(stdout, "scrypt:n=10,r=8,p=1") {
+		t.Errorf("status after rekey = %d, %q, want scrypt", code, stdout)
+	}
+	if code, _, stderr := runCLI(t, nil, "", nil, "decrypt", "--passphrase-file", passphraseFile, files[0]); code != exitOK || readFile(t, files[0]) != testState {
+		t.Errorf("decrypt with the new passphrase = %d, %q", code, stderr)
+	}
+}
+
+func TestCLIBackup(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate")
+	env := map[string]string{"TFSTATE_PASSPHRASE": "passphrase", "TFSTATE_KDF": testKDF}
+
+	for _, command := range []string{"encrypt", "decrypt"} {
+		if code, _, stderr := runCLI(t, env, "", nil, command, "--backup", files[0]); code != exitOK {
+			t.Fatalf("%s --backup = %d, %s", command, code, stderr)
+		}
+		if _, err := os.Stat(files[0] + ".bak"); !os.IsNotExist(err) {
+			t.Errorf("%s --backup left the backup after a verified write: %v", command, err)
+		}
+	}
+	if got := readFile(t, files[0]); got != testState {
+		t.Errorf("state = %q after encrypt and decrypt with backups", got)
+	}
+
+	if code, _, stderr := runCLI(t, env, "", nil, "status", "--backup", files[0]); code != exitUsage || !strings.Contains(stderr, "flag provided but not defined") {
+		t.Errorf("status --backup = %d, %q, want a usage error", code, stderr)
+	}
+}
+
+func TestValidJSON(t *testing.T) {
+	tests := map[string]bool{
+		testState:          true,
+		`[1, {"a": [2]}]`:  true,
+		`4`:                true,
+		``:                 false,
+		`{"version": 4`:    false,
+		`{"version" 4}`:    false,
+		`{"version": 4}{}`: false,
+		`not json`:         false,
+	}
+	for data, want := range tests {
+		if err := validJSON(strings.NewReader(data)); (err == nil) != want {
+			t.Errorf("validJSON(%q) error = %v, want valid %t", data, err, want)
+		}
+	}
+}
+
+func TestCLISecretKey(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate")
+	key := []string{"--key-secret-id", "7", "--key-field", "key"}
+
+	code, _, stderr := runCLI(t, nil, "", nil, append(append([]string{"encrypt"}, key...), files[0])...)
+	if code != exitOK {
+		t.Fatalf("encrypt with a key secret = %d, %q", code, stderr)
+	}
+	if code, stdout, _ := runCLI(t, nil, "", nil, "status", files[0]); !strings.Contains(stdout, "encrypted, hkdf") {
+		t.Errorf("status = %d, %q, want a file encrypted with a key", code, stdout)
+	}
+
+	code, _, stderr = runCLI(t, map[string]string{"TFSTATE_PASSPHRASE": "passphrase"}, "", nil, "decrypt", files[0])
+	if code != exitFailure || !strings.Contains(stderr, "not with a passphrase") {
+		t.Errorf("decrypt with a passphrase = %d, %q, want a failure", code, stderr)
+	}
+	if code, stdout, stderr := runCLI(t, nil, "", nil, append(append([]string{"verify"}, key...), files[0])...); code != exitOK {
+		t.Errorf("verify with a key secret = %d, %q, %q", code, stdout, stderr)
+	}
+
+	// The environment names the key secret as well
+	env := map[string]string{"TFSTATE_KEY_SECRET_ID": "7", "TFSTATE_KEY_FIELD": "key"}
+	if code, _, stderr := runCLI(t, env, "", nil, "decrypt", files[0]); code != exitOK || readFile(t, files[0]) != testState {
+		t.Errorf("decrypt with a key secret from the environment = %d, %q", code, stderr)
+	}
+}
+
+func TestCLIRekeySecretKey(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate")
+	env := map[string]string{"TFSTATE_PASSPHRASE": "passphrase", "TFSTATE_KDF": testKDF}
+	if code, _, stderr := runCLI(t, env, "", nil, "encrypt", files[0]); code != exitOK {
+		t.Fatalf("encrypt = %d, %q", code, stderr)
+	}
+
+	// From the passphrase to a key, which is used without a key derivation function
+	code, _, stderr := runCLI(t, env, "", nil, "rekey", "--new-key-secret-id", "7", "--new-key-field", "key", files[0])
+	if code != exitOK {
+		t.Fatalf("rekey from a passphrase to a key = %d, %q", code, stderr)
+	}
+	if code, stdout, _ := runCLI(t, nil, "", nil, "status", files[0]); !strings.Contains(stdout, "encrypted, hkdf") {
+		t.Errorf("status after rekey to a key = %d, %q, want hkdf", code, stdout)
+	}
+
+	// From one key to another, with the keys named in the environment
+	keys := map[string]string{
+		"TFSTATE_KEY_SECRET_ID": "7", "TFSTATE_KEY_FIELD": "key",
+		"TFSTATE_NEW_KEY_SECRET_ID": "9", "TFSTATE_NEW_KEY_FIELD": "key",
+	}
+	if code, _, stderr := runCLI(t, keys, "", nil, "rekey", "--backup", files[0]); code != exitOK {
+		t.Fatalf("rekey from a key to a key = %d, %q", code, stderr)
+	}
+	if code, _, stderr := runCLI(t, nil, "", nil, "verify", "--key-secret-id", "7", "--key-field", "key", files[0]); code != exitFailure {
+		t.Errorf("verify with the old key = %d, %q, want a failure", code, stderr)
+	}
+
+	// From the key back to a passphrase
+	code, _, stderr = runCLI(t, map[string]string{"TFSTATE_NEW_PASSPHRASE": "new", "TFSTATE_KDF": testKDF}, "", nil,
+		"rekey", "--key-secret-id", "9", "--key-field", "key", files[0])
+	if code != exitOK {
+		t.Fatalf("rekey from a key to a passphrase = %d, %q", code, stderr)
+	}
+	if code, stdout, _ := runCLI(t, nil, "", nil, "status", files[0]); !strings.Contains(stdout, "argon2id") {
+		t.Errorf("status after rekey to a passphrase = %d, %q, want argon2id", code, stdout)
+	}
+	if code, _, stderr := runCLI(t, map[string]string{"TFSTATE_PASSPHRASE": "new"}, "", nil, "decrypt", files[0]); code != exitOK || readFile(t, files[0]) != testState {
+		t.Errorf("decrypt with the new passphrase = %d, %q", code, stderr)
+	}
+
+	// The new key is checked like the current one
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--new-key-field", "key"}, wantErr: "--new-key-field needs --new-key-secret-id"},
+		{args: []string{"--new-key-secret-id", "7"}, wantErr: "--new-key-field or TFSTATE_NEW_KEY_FIELD"},
+		{args: []string{"--new-key-secret-id", "7", "--new-key-field", "key", "--kdf", "scrypt"}, wantErr: "--kdf cannot be used with a key"},
+		{args: []string{"--new-key-secret-id", "7", "--new-key-field", "other"}, wantErr: "does not hold a key"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"rekey"}, tt.args...), files[0])
+		code, _, stderr := runCLI(t, env, "", nil, args...)
+		if code != exitUsage || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("%v = %d, %q, want a usage error with %q", args, code, stderr, tt.wantErr)
+		}
+	}
+}
+
+func TestCLISecretKeyErrors(t *testing.T) {
+	files := writeFiles(t, testState, "terraform.tfstate")
+
+	tests := []struct {
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{args: []string{"--key-field", "key"}, wantErr: "--key-field needs --key-secret-id"},
+		{args: []string{"--key-secret-id", "7"}, wantErr: "--key-field"},
+		{args: []string{"--key-secret-id", "7", "--key-field", "key", "--kdf", "scrypt"}, wantErr: "cannot be used with a key"},
+		{args: []string{"--key-secret-id", "8", "--key-field", "key"}, wantErr: "failed to read the key from secret 8: 404"},
+		{args: []string{"--key-secret-id", "7", "--key-field", "other"}, wantErr: "does not hold a key"},
+		{env: map[string]string{"TFSTATE_KEY_SECRET_ID": "seven"}, wantErr: "not a secret ID"},
+	}
+	for _, tt := range tests {
+		args := append(append([]string{"encrypt"}, tt.args...), files[0])
+		code, _, stderr := runCLI(t, tt.env, "", nil, args...)
+		if code != exitUsage || !strings.Contains(stderr, tt.wantErr) {
+			t.Errorf("%v = %d, %q, want a usage error with %q", args, code, stderr, tt.wantErr)
+		}
+	}
+	if got := readFile(t, files[0]); got != testState {
+		t.Errorf("state = %q after failed commands, want it unchanged", got)
+	}
+
+	// Without a server, the provider settings are missing
+	c := &cli{getenv: func(string) string { return "" }}
+	if _, err := c.readSecretField(7, "key"); err == nil || !strings.Contains(err.Error(), "TSS_SERVER_URL") {
+		t.Errorf("readSecretField() without a server error = %v, want a hint at TSS_SERVER_URL", err)
+	}
+}
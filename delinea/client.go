@@ -0,0 +1,552 @@
+package delinea
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// tokenRefreshRatio is the share of a token's lifetime after which it is renewed
+const tokenRefreshRatio = 0.9
+
+// defaultMaxConcurrentRequests is used when max_concurrent_requests is not configured
+const defaultMaxConcurrentRequests = 5
+
+// ClientOptions holds the provider settings that control how the client calls the server
+type ClientOptions struct {
+	MaxConcurrentRequests int           // Upper bound of requests in flight across the whole client
+	MaxRetries            int           // Retries of a request after a transient error, 0 to fail at once
+	Backoff               time.Duration // Delay before the first retry, doubled for every further one
+	Jitter                bool          // Randomize the delays between retries
+}
+
+// Client is the Secret Server client shared by every resource, data source and
+// ephemeral resource of a configured provider. It authenticates once, caches the
+// access token and refreshes it centrally when it expires or is rejected. Secrets
+// and templates go through the SDK server bound to that token; the client only
+// sends its own requests to the endpoints the SDK does not cover.
+type Client struct {
+	config     server.Configuration // Store the provider configuration
+	options    ClientOptions
+	httpClient *http.Client
+
+	fingerprintKey []byte // Random key for fingerprints of secret values, never stored
+
+	requests chan struct{} // Semaphore that limits the calls in flight to MaxConcurrentRequests
+
+	mu        sync.Mutex
+	server    *server.Server // SDK server bound to the cached access token and the vault URL
+	token     string
+	expiresAt time.Time // Zero when the token does not expire
+}
+
+// NewClient returns a client for the given configuration. No request is made
+// until the first API call.
+func NewClient(config server.Configuration, options ClientOptions) (*Client, error) {
+	if config.ServerURL == "" {
+		return nil, fmt.Errorf("the Secret Server URL is not set")
+	}
+	if config.Credentials.Token == "" && config.Credentials.Username == "" {
+		return nil, fmt.Errorf("either a username and password or a token must be set")
+	}
+
+	if options.MaxConcurrentRequests < 1 {
+		options.MaxConcurrentRequests = defaultMaxConcurrentRequests
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = defaultBackoff
+	}
+
+	fingerprintKey := make([]byte, sha256.Size)
+	if _, err := rand.Read(fingerprintKey); err != nil {
+		return nil, fmt.Errorf("failed to generate a fingerprint key: %w", err)
+	}
+
+	return &Client{
+		config:         config,
+		options:        options,
+		httpClient:     &http.Client{},
+		fingerprintKey: fingerprintKey,
+		requests:       make(chan struct{}, options.MaxConcurrentRequests),
+	}, nil
+}
+
+// fingerprint returns a keyed hash of a secret value, which tells whether the value
+// changed without revealing it. Fingerprints can only be compared within the same
+// provider process, as the key is not kept anywhere else.
+func (c *Client) fingerprint(value string) string {
+	mac := hmac.New(sha256.New, c.fingerprintKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Secret gets the secret with the given ID. The SDK replaces the placeholder values
+// of file fields with the attachments.
+func (c *Client) Secret(ctx context.Context, id int) (*server.Secret, error) {
+	var secret *server.Secret
+	err := c.call(ctx, func(s *server.Server) (err error) {
+		secret, err = s.Secret(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// SecretSummary is the summary of a secret, which holds no field values
+type SecretSummary struct {
+	ID       int
+	Name     string
+	FolderID int
+	Active   bool
+}
+
+// SecretSummary gets the summary of the secret with the given ID. Unlike Secret, it
+// is not recorded as a view in the audit log and works for secrets that require a
+// comment or checkout. Deactivated secrets are returned with Active false.
+func (c *Client) SecretSummary(ctx context.Context, id int) (*SecretSummary, error) {
+	summary := new(SecretSummary)
+	if err := c.do(ctx, "GET", fmt.Sprintf("secrets/%d/summary", id), nil, nil, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// SecretsByID gets the secrets with the given IDs, running up to MaxConcurrentRequests
+// requests at a time. The returned secrets and errors are in the same order as ids,
+// with exactly one of the two set for each ID.
+func (c *Client) SecretsByID(ctx context.Context, ids []int) ([]*server.Secret, []error) {
+	secrets := make([]*server.Secret, len(ids))
+	errs := make([]error, len(ids))
+
+	c.concurrently(len(ids), func(i int) {
+		secrets[i], errs[i] = c.Secret(ctx, ids[i])
+	})
+
+	return secrets, errs
+}
+
+// concurrently calls fn for every index below n, running up to MaxConcurrentRequests
+// calls at a time, and returns when all calls are done. The requests of the calls
+// still share the limit of the client with all other requests.
+func (c *Client) concurrently(n int, fn func(i int)) {
+	workers := min(c.options.MaxConcurrentRequests, n)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// CreateSecret creates the given secret and returns it as stored by the server. It is
+// not retried after a transient error: the SDK writes the secret and its attachments
+// in several requests without telling which one failed, so the secret may already
+// exist and a retry would create it a second time.
+func (c *Client) CreateSecret(ctx context.Context, secret server.Secret) (*server.Secret, error) {
+	var created *server.Secret
+	err := c.callOnce(ctx, func(s *server.Server) (err error) {
+		created, err = s.CreateSecret(secret)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateSecret updates the given secret and returns it as stored by the server. SSH
+// keys can only be generated when a secret is created.
+func (c *Client) UpdateSecret(ctx context.Context, secret server.Secret) (*server.Secret, error) {
+	var updated *server.Secret
+	err := c.call(ctx, func(s *server.Server) (err error) {
+		updated, err = s.UpdateSecret(secret)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteSecret deletes the secret with the given ID
+func (c *Client) DeleteSecret(ctx context.Context, id int) error {
+	return c.call(ctx, func(s *server.Server) error {
+		return s.DeleteSecret(id)
+	})
+}
+
+// SecretTemplate gets the secret template with the given ID
+func (c *Client) SecretTemplate(ctx context.Context, id int) (*server.SecretTemplate, error) {
+	var template *server.SecretTemplate
+	err := c.call(ctx, func(s *server.Server) (err error) {
+		template, err = s.SecretTemplate(id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// call runs an SDK call against the server bound to the cached access token. It waits
+// while MaxConcurrentRequests calls of the client are in flight, whichever resource
+// made them, runs the call again once with a new token if the server rejected the
+// cached one, and retries transient errors with backoff. The SDK takes no context, so
+// a request it has sent runs to completion; ctx is checked before every attempt.
+func (c *Client) call(ctx context.Context, fn func(s *server.Server) error) error {
+	return c.retry(ctx, func() error {
+		return c.callOnce(ctx, fn)
+	})
+}
+
+// callOnce is call without the retries after transient errors. A call rejected for
+// its token is still run again, as the server rejects the token on the first request
+// of the call, before anything is written.
+func (c *Client) callOnce(ctx context.Context, fn func(s *server.Server) error) error {
+	return c.withToken(ctx, func(s *server.Server) error {
+		select {
+		case c.requests <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-c.requests }()
+
+		return fn(s)
+	})
+}
+
+// do calls an endpoint of the Secret Server REST API that the SDK does not cover, such
+// as folders, check-out and restricted views. path is relative to /api/v1, input is
+// sent as JSON and a JSON response is decoded into output. If output is a *[]byte, it
+// is set to the response body as is.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, input, output interface{}) error {
+	var body []byte
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the request body: %w", err)
+		}
+		body = data
+	}
+
+	return c.call(ctx, func(s *server.Server) error {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		requestURL := strings.TrimRight(s.ServerURL, "/") + "/api/v1/" + strings.TrimLeft(path, "/")
+		if len(query) > 0 {
+			requestURL += "?" + query.Encode()
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+s.Credentials.Token)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		tflog.SubsystemDebug(ctx, logClient, "Calling the Secret Server API", map[string]interface{}{
+			"method": method,
+			"url":    req.URL.String(),
+		})
+
+		data, err := c.send(req)
+		if err != nil {
+			return err
+		}
+		if raw, ok := output.(*[]byte); ok {
+			*raw = data
+			return nil
+		}
+		if output == nil || len(data) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(data, output); err != nil {
+			return fmt.Errorf("failed to parse the response from %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// withToken runs call against an authenticated SDK server, renewing a rejected token once
+func (c *Client) withToken(ctx context.Context, call func(s *server.Server) error) error {
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s, token, err := c.authenticatedServer(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = call(s)
+		if err == nil || !isUnauthorized(err) || attempt > 0 || c.config.Credentials.Token != "" {
+			return err
+		}
+
+		tflog.SubsystemDebug(ctx, logClient, "Access token was rejected, requesting a new one")
+		c.invalidateToken(token)
+	}
+}
+
+// authenticatedServer returns the SDK server bound to a valid access token,
+// requesting a new token when there is none or the cached one is about to expire
+func (c *Client) authenticatedServer(ctx context.Context) (*server.Server, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.server != nil && (c.expiresAt.IsZero() || time.Now().Before(c.expiresAt)) {
+		return c.server, c.token, nil
+	}
+
+	serverURL, token, expiresIn, err := c.requestToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The SDK uses a configured token as is, which skips its own health checks
+	// and token requests on every call
+	config := c.config
+	config.ServerURL = serverURL
+	config.Credentials = server.UserCredential{Token: token}
+
+	s, err := server.New(config)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create server client: %w", err)
+	}
+
+	c.server = s
+	c.token = token
+	c.expiresAt = time.Time{}
+	if expiresIn > 0 {
+		c.expiresAt = time.Now().Add(time.Duration(float64(expiresIn)*tokenRefreshRatio) * time.Second)
+	}
+
+	return c.server, c.token, nil
+}
+
+// invalidateToken drops the cached token unless it was already replaced
+func (c *Client) invalidateToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.server = nil
+		c.token = ""
+	}
+}
+
+// requestToken gets an access token for the configured credentials. It returns the
+// URL to use for API calls, the token and its lifetime in seconds (0 if unlimited).
+func (c *Client) requestToken(ctx context.Context) (string, string, int, error) {
+	baseURL := strings.TrimRight(c.config.ServerURL, "/")
+
+	if c.config.Credentials.Token != "" {
+		return baseURL, c.config.Credentials.Token, 0, nil
+	}
+
+	// Secret Server answers on healthcheck.aspx, Delinea Platform on health
+	if c.isHealthy(ctx, baseURL+"/healthcheck.aspx") {
+		tflog.SubsystemDebug(ctx, logClient, "Requesting an access token from Secret Server")
+
+		values := url.Values{
+			"username":   {c.config.Credentials.Username},
+			"password":   {c.config.Credentials.Password},
+			"grant_type": {"password"},
+		}
+		if c.config.Credentials.Domain != "" {
+			values.Set("domain", c.config.Credentials.Domain)
+		}
+
+		grant, err := c.postTokenRequest(ctx, baseURL+"/oauth2/token", values)
+		if err != nil {
+			return "", "", 0, err
+		}
+		return baseURL, grant.AccessToken, grant.ExpiresIn, nil
+	}
+
+	if c.isHealthy(ctx, baseURL+"/health") {
+		tflog.SubsystemDebug(ctx, logClient, "Requesting an access token from Delinea Platform")
+
+		values := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {c.config.Credentials.Username},
+			"client_secret": {c.config.Credentials.Password},
+			"scope":         {"xpmheadless"},
+		}
+
+		grant, err := c.postTokenRequest(ctx, baseURL+"/identity/api/oauth2/token/xpmplatform", values)
+		if err != nil {
+			return "", "", 0, err
+		}
+
+		vaultURL, err := c.defaultVaultURL(ctx, baseURL, grant.AccessToken)
+		if err != nil {
+			return "", "", 0, err
+		}
+		return strings.TrimRight(vaultURL, "/"), grant.AccessToken, grant.ExpiresIn, nil
+	}
+
+	return "", "", 0, fmt.Errorf("the server at %s is neither a healthy Secret Server nor Delinea Platform", baseURL)
+}
+
+// tokenGrant is the response of an OAuth2 token request
+type tokenGrant struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// postTokenRequest posts a form-encoded OAuth2 token request
+func (c *Client) postTokenRequest(ctx context.Context, tokenURL string, values url.Values) (*tokenGrant, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	data, err := c.send(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get an access token: %w", err)
+	}
+
+	grant := new(tokenGrant)
+	if err := json.Unmarshal(data, grant); err != nil {
+		return nil, fmt.Errorf("failed to parse the token response: %w", err)
+	}
+	if grant.AccessToken == "" {
+		return nil, fmt.Errorf("the token response did not contain an access token")
+	}
+	return grant, nil
+}
+
+// defaultVaultURL returns the URL of the default, active vault of a Platform tenant
+func (c *Client) defaultVaultURL(ctx context.Context, baseURL, accessToken string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/vaultbroker/api/vaults", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vaults request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	data, err := c.send(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to list vaults: %w", err)
+	}
+
+	var vaults server.VaultsResponseModel
+	if err := json.Unmarshal(data, &vaults); err != nil {
+		return "", fmt.Errorf("failed to parse the vaults response: %w", err)
+	}
+	for _, vault := range vaults.Vaults {
+		if vault.IsDefault && vault.IsActive {
+			return vault.Connection.Url, nil
+		}
+	}
+	return "", fmt.Errorf("no configured vault found")
+}
+
+// isHealthy reports whether the health endpoint at healthURL reports a healthy server
+func (c *Client) isHealthy(ctx context.Context, healthURL string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
+	if err != nil {
+		return false
+	}
+
+	data, err := c.send(req)
+	if err != nil {
+		tflog.SubsystemDebug(ctx, logClient, "Health check failed", map[string]interface{}{
+			"url":   healthURL,
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	var health server.Response
+	if err := json.Unmarshal(data, &health); err == nil {
+		return health.Healthy
+	}
+	return bytes.Contains(data, []byte("Healthy"))
+}
+
+// send performs the request and returns the body of a 2xx response. Other
+// responses are returned as a *responseError, which keeps the Retry-After header.
+func (c *Client) send(req *http.Request) ([]byte, error) {
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if len(data) > 255 {
+			data = append(data[:255], "..."...)
+		}
+		return nil, &responseError{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       string(data),
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return data, nil
+}
+
+// isUnauthorized reports whether err is an HTTP 401 response
+func isUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// isNotFound reports whether err is an HTTP 404 response
+func isNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// hasStatus reports whether err is a response with the given HTTP status code.
+// The SDK reports these as errors that start with the status.
+func hasStatus(err error, statusCode int) bool {
+	var response *responseError
+	if errors.As(err, &response) {
+		return response.StatusCode == statusCode
+	}
+	return err != nil && strings.HasPrefix(err.Error(), fmt.Sprintf("%d ", statusCode))
+}
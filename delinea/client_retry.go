@@ -0,0 +1,111 @@
+package delinea
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Defaults for the retry settings of the provider
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = time.Second
+)
+
+// maxBackoff caps the exponential backoff between two attempts. A longer delay
+// requested by the server with Retry-After is still honored.
+const maxBackoff = 30 * time.Second
+
+// responseError is a non-2xx response to a request sent by the client itself. Its
+// message starts with the HTTP status, the same way the SDK reports failed requests.
+type responseError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration // Zero when the response did not include Retry-After
+}
+
+func (e *responseError) Error() string {
+	return e.Status + ": " + e.Body
+}
+
+// retry calls fn until it succeeds, fails with an error that is not transient or
+// MaxRetries retries are used up. The delay between attempts doubles from Backoff
+// on, honors the server's Retry-After and ends early when ctx is cancelled.
+func (c *Client) retry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil || !isTransient(err) || attempt >= c.options.MaxRetries {
+			return err
+		}
+
+		delay := c.retryDelay(attempt, err)
+		tflog.SubsystemWarn(ctx, logClient, "Secret Server request failed with a transient error, retrying", map[string]interface{}{
+			"attempt": attempt + 1,
+			"delay":   delay.String(),
+			"error":   err.Error(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying after the given failed attempt,
+// counted from 0. With Jitter the backoff is spread randomly over [0, backoff) so that
+// concurrent requests do not retry in lockstep.
+func (c *Client) retryDelay(attempt int, err error) time.Duration {
+	backoff := maxBackoff
+	if attempt < 32 {
+		backoff = min(c.options.Backoff<<attempt, maxBackoff)
+	}
+	if backoff <= 0 {
+		backoff = maxBackoff
+	}
+	if c.options.Jitter {
+		backoff = rand.N(backoff)
+	}
+
+	var response *responseError
+	if errors.As(err, &response) && response.RetryAfter > backoff {
+		return response.RetryAfter
+	}
+	return backoff
+}
+
+// isTransient reports whether err means that the server did not handle the request
+// because it is rate limiting or temporarily unavailable, so it is safe to send again
+func isTransient(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests) || hasStatus(err, http.StatusServiceUnavailable)
+}
+
+// parseRetryAfter returns the delay given by a Retry-After header, which is either a
+// number of seconds or an HTTP date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
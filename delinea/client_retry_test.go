@@ -0,0 +1,198 @@
+package delinea
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+)
+
+// newRetryingTestClient returns a test client that retries quickly and without jitter
+func newRetryingTestClient(t *testing.T, f *fakeSecretServer, maxRetries int, backoff time.Duration) *Client {
+	t.Helper()
+
+	client, err := NewClient(server.Configuration{
+		ServerURL:   f.URL,
+		Credentials: server.UserCredential{Username: fakeUsername, Password: fakePassword},
+	}, ClientOptions{MaxRetries: maxRetries, Backoff: backoff})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, nil)
+	client := newRetryingTestClient(t, f, 3, time.Millisecond)
+	f.failNext("GET /api/v1/secrets/"+strconv.Itoa(id), http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	if _, err := client.Secret(context.Background(), id); err != nil {
+		t.Fatalf("Secret() error = %v", err)
+	}
+	if got := f.requestCount("GET /api/v1/secrets/" + strconv.Itoa(id)); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newRetryingTestClient(t, f, 2, time.Millisecond)
+	f.failNext("DELETE /api/v1/secrets/1", http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests)
+
+	err := client.DeleteSecret(context.Background(), 1)
+	if !hasStatus(err, http.StatusTooManyRequests) {
+		t.Errorf("DeleteSecret() error = %v, want 429", err)
+	}
+	if got := f.requestCount("DELETE /api/v1/secrets/1"); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestClientDoesNotRetryOtherErrors(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newRetryingTestClient(t, f, 3, time.Millisecond)
+	f.failNext("GET /api/v1/secret-templates/1", http.StatusInternalServerError)
+
+	if _, err := client.SecretTemplate(context.Background(), 1); !hasStatus(err, http.StatusInternalServerError) {
+		t.Errorf("SecretTemplate() error = %v, want 500", err)
+	}
+	if got := f.requestCount("GET /api/v1/secret-templates/1"); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestClientHonorsRetryAfter(t *testing.T) {
+	f := newFakeSecretServer(t)
+	folder := f.addFolder("Apps", rootFolderID)
+	client := newRetryingTestClient(t, f, 1, time.Millisecond)
+	f.setRetryAfter("1")
+	f.failNext("GET /api/v1/folders/"+strconv.Itoa(folder), http.StatusTooManyRequests)
+
+	start := time.Now()
+	if _, err := client.Folder(context.Background(), folder); err != nil {
+		t.Fatalf("Folder() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Folder() retried after %s, want at least the 1s from Retry-After", elapsed)
+	}
+}
+
+func TestClientDoesNotRetryCreateSecret(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newRetryingTestClient(t, f, 3, time.Millisecond)
+
+	// The secret is created, but reading it back fails. A retry would create it again.
+	id := strconv.Itoa(f.nextID + 1)
+	f.failNext("GET /api/v1/secrets/"+id, http.StatusServiceUnavailable)
+
+	_, err := client.CreateSecret(context.Background(), server.Secret{
+		Name:             "web",
+		SecretTemplateID: loginTemplateID,
+		Fields:           []server.SecretField{{Slug: "username", ItemValue: "admin"}},
+	})
+	if !hasStatus(err, http.StatusServiceUnavailable) {
+		t.Errorf("CreateSecret() error = %v, want 503", err)
+	}
+	if got := f.requestCount("POST /api/v1/secrets/"); got != 1 {
+		t.Errorf("create requests = %d, want 1", got)
+	}
+	if got := len(f.secretIDs()); got != 1 {
+		t.Errorf("secrets = %d, want 1", got)
+	}
+}
+
+func TestClientRetriesUpdateSecret(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, map[string]string{"username": "admin"})
+	client := newRetryingTestClient(t, f, 3, time.Millisecond)
+	f.failNext("PUT /api/v1/secrets/"+strconv.Itoa(id), http.StatusTooManyRequests)
+
+	updated, err := client.UpdateSecret(context.Background(), server.Secret{
+		ID:               id,
+		Name:             "web",
+		SecretTemplateID: loginTemplateID,
+		Fields:           []server.SecretField{{Slug: "username", ItemValue: "operator"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateSecret() error = %v", err)
+	}
+	if value, _ := updated.Field("username"); value != "operator" {
+		t.Errorf("Field(username) = %q, want the updated value", value)
+	}
+	if got := f.requestCount("PUT /api/v1/secrets/" + strconv.Itoa(id)); got != 2 {
+		t.Errorf("update requests = %d, want 2", got)
+	}
+}
+
+func TestClientRetryStopsWhenCancelled(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newRetryingTestClient(t, f, 3, time.Hour)
+	f.failNext("GET /api/v1/secrets/1", http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := client.Secret(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || !hasStatus(err, http.StatusServiceUnavailable) {
+		t.Errorf("Secret() error = %v, want the 503 and the context error", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	client := &Client{options: ClientOptions{Backoff: time.Second}}
+	transient := errors.New("503 Service Unavailable: ")
+
+	tests := []struct {
+		name    string
+		attempt int
+		err     error
+		want    time.Duration
+	}{
+		{name: "first retry", attempt: 0, err: transient, want: time.Second},
+		{name: "doubles", attempt: 2, err: transient, want: 4 * time.Second},
+		{name: "capped", attempt: 10, err: transient, want: maxBackoff},
+		{name: "no overflow", attempt: 100, err: transient, want: maxBackoff},
+		{name: "longer Retry-After", attempt: 0, err: &responseError{StatusCode: 429, RetryAfter: 5 * time.Second}, want: 5 * time.Second},
+		{name: "shorter Retry-After", attempt: 3, err: &responseError{StatusCode: 429, RetryAfter: time.Second}, want: 8 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := client.retryDelay(tt.attempt, tt.err); got != tt.want {
+				t.Errorf("retryDelay() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	client.options.Jitter = true
+	for range 100 {
+		if got := client.retryDelay(1, transient); got < 0 || got >= 2*time.Second {
+			t.Fatalf("retryDelay() with jitter = %s, want within [0s, 2s)", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "120", want: 2 * time.Minute},
+		{value: "-5", want: 0},
+		{value: "Wed, 01 May 2024 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Wed, 01 May 2024 11:00:00 GMT", want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+)
+
+// newTestClient returns a client for the fake server that logs in with a username
+// and password
+func newTestClient(t *testing.T, f *fakeSecretServer) *Client {
+	t.Helper()
+
+	client, err := NewClient(server.Configuration{
+		ServerURL:   f.URL,
+		Credentials: server.UserCredential{Username: fakeUsername, Password: fakePassword},
+	}, ClientOptions{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestClientReusesAccessToken(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, map[string]string{"username": "admin"})
+	client := newTestClient(t, f)
+
+	for range 3 {
+		if _, err := client.Secret(context.Background(), id); err != nil {
+			t.Fatalf("Secret() error = %v", err)
+		}
+	}
+
+	if got := f.tokenRequestCount(); got != 1 {
+		t.Errorf("token requests = %d, want 1", got)
+	}
+}
+
+func TestClientRenewsRejectedToken(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, nil)
+	client := newTestClient(t, f)
+
+	if _, err := client.Secret(context.Background(), id); err != nil {
+		t.Fatalf("Secret() error = %v", err)
+	}
+	f.revokeTokens()
+	if _, err := client.Secret(context.Background(), id); err != nil {
+		t.Fatalf("Secret() after revoking the token error = %v", err)
+	}
+
+	if got := f.tokenRequestCount(); got != 2 {
+		t.Errorf("token requests = %d, want 2", got)
+	}
+}
+
+func TestClientRejectsWrongPassword(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client, err := NewClient(server.Configuration{
+		ServerURL:   f.URL,
+		Credentials: server.UserCredential{Username: fakeUsername, Password: "wrong"},
+	}, ClientOptions{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	_, err = client.Secret(context.Background(), 1)
+	if err == nil || !strings.Contains(err.Error(), "failed to get an access token") {
+		t.Errorf("Secret() error = %v, want a token error", err)
+	}
+}
+
+func TestClientUsesConfiguredToken(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client, err := NewClient(server.Configuration{
+		ServerURL:   f.URL,
+		Credentials: server.UserCredential{Token: "configured-token"},
+	}, ClientOptions{})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	// The fake server does not know the token, and a configured token is never renewed
+	_, err = client.Secret(context.Background(), 1)
+	if !isUnauthorized(err) {
+		t.Errorf("Secret() error = %v, want 401", err)
+	}
+	if got := f.tokenRequestCount(); got != 0 {
+		t.Errorf("token requests = %d, want 0", got)
+	}
+}
+
+func TestClientSecretsByIDKeepsOrder(t *testing.T) {
+	f := newFakeSecretServer(t)
+	var ids []int
+	for i := range 8 {
+		ids = append(ids, f.addSecret(fmt.Sprintf("secret-%d", i), 0, loginTemplateID, nil))
+	}
+	ids = append(ids, 9999)
+	client := newTestClient(t, f)
+
+	secrets, errs := client.SecretsByID(context.Background(), ids)
+
+	for i, id := range ids[:8] {
+		if errs[i] != nil {
+			t.Fatalf("SecretsByID() error for %d = %v", id, errs[i])
+		}
+		if secrets[i].ID != id {
+			t.Errorf("SecretsByID()[%d].ID = %d, want %d", i, secrets[i].ID, id)
+		}
+	}
+	if !isNotFound(errs[8]) || secrets[8] != nil {
+		t.Errorf("SecretsByID() for a missing secret = %v, %v, want a 404 error", secrets[8], errs[8])
+	}
+}
+
+func TestClientDownloadsAttachments(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newTestClient(t, f)
+
+	created, err := client.CreateSecret(context.Background(), server.Secret{
+		Name:             "certificate",
+		SecretTemplateID: fileTemplateID,
+		Fields: []server.SecretField{
+			{Slug: "username", ItemValue: "admin"},
+			{Slug: "certificate", ItemValue: "-----BEGIN CERTIFICATE-----", Filename: "cert.pem"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+
+	file, ok := f.file(created.ID, "certificate")
+	if !ok || string(file.Content) != "-----BEGIN CERTIFICATE-----" || file.Name != "cert.pem" {
+		t.Errorf("uploaded file = %+v, want cert.pem with the certificate", file)
+	}
+	value, _ := created.Field("certificate")
+	if value != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("Field(certificate) = %q, want the downloaded certificate", value)
+	}
+}
+
+func TestSecretWithAccess(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newTestClient(t, f)
+	ctx := context.Background()
+
+	created, err := client.CreateSecret(ctx, server.Secret{
+		Name:             "certificate",
+		SecretTemplateID: fileTemplateID,
+		Fields: []server.SecretField{
+			{Slug: "certificate", ItemValue: "-----BEGIN CERTIFICATE-----", Filename: "cert.pem"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSecret() error = %v", err)
+	}
+	f.requireComment(created.ID)
+
+	if _, err := client.SecretWithAccess(ctx, created.ID, secretAccess{}); !hasStatus(err, http.StatusBadRequest) {
+		t.Errorf("SecretWithAccess() without a comment error = %v, want 400", err)
+	}
+
+	access := secretAccess{Comment: "rotating the certificate", TicketNumber: "CHG-42", TicketSystemID: 3}
+	secret, err := client.SecretWithAccess(ctx, created.ID, access)
+	if err != nil {
+		t.Fatalf("SecretWithAccess() error = %v", err)
+	}
+	if value, _ := secret.Field("certificate"); value != "-----BEGIN CERTIFICATE-----" {
+		t.Errorf("Field(certificate) = %q, want the downloaded certificate", value)
+	}
+	if got := f.viewJustifications(created.ID); len(got) != 1 || got[0] != access {
+		t.Errorf("view justifications = %+v, want %+v", got, access)
+	}
+}
+
+func TestClientLimitsConcurrentRequests(t *testing.T) {
+	f := newFakeSecretServer(t)
+	var ids []int
+	for i := range 4 {
+		ids = append(ids, f.addSecret(fmt.Sprintf("secret-%d", i), 0, loginTemplateID, nil))
+	}
+	client, err := NewClient(server.Configuration{
+		ServerURL:   f.URL,
+		Credentials: server.UserCredential{Username: fakeUsername, Password: fakePassword},
+	}, ClientOptions{MaxConcurrentRequests: 2})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	f.setLatency(10 * time.Millisecond)
+
+	// Separate bulk reads, such as those of two data sources, share the limit
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.SecretsByID(context.Background(), ids)
+		}()
+	}
+	wg.Wait()
+
+	if got := f.maxConcurrentRequests(); got != 2 {
+		t.Errorf("concurrent requests = %d, want 2", got)
+	}
+}
+
+func TestSecretIDByName(t *testing.T) {
+	f := newFakeSecretServer(t)
+	prod := f.addFolder("Prod", rootFolderID)
+	dev := f.addFolder("Dev", rootFolderID)
+	prodID := f.addSecret("db", prod, loginTemplateID, nil)
+	f.addSecret("db", dev, loginTemplateID, nil)
+	f.addSecret("db-replica", prod, loginTemplateID, nil)
+	client := newTestClient(t, f)
+
+	tests := []struct {
+		name, secretName, folderPath string
+		wantID                       int
+		wantErr                      string
+	}{
+		{name: "in folder", secretName: "db", folderPath: `\Prod`, wantID: prodID},
+		{name: "folder with slashes", secretName: "DB", folderPath: "prod/", wantID: prodID},
+		{name: "ambiguous", secretName: "db", wantErr: "2 secrets named 'db' were found"},
+		{name: "missing", secretName: "cache", wantErr: "no secret named 'cache' was found"},
+		{name: "missing folder", secretName: "db", folderPath: `\Test`, wantErr: `no folder found at path '\Test'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretID, err := client.SecretIDByName(context.Background(), tt.secretName, tt.folderPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("SecretIDByName() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SecretIDByName() error = %v", err)
+			}
+			if secretID != tt.wantID {
+				t.Errorf("SecretIDByName() = %d, want %d", secretID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestSecretIDByNameReadsAllPages(t *testing.T) {
+	f := newFakeSecretServer(t)
+	for i := range secretSearchPageSize + 10 {
+		f.addSecret(fmt.Sprintf("db-%03d", i), rootFolderID, loginTemplateID, nil)
+	}
+	id := f.addSecret("db", rootFolderID, loginTemplateID, nil)
+	client := newTestClient(t, f)
+
+	secretID, err := client.SecretIDByName(context.Background(), "db", "")
+	if err != nil {
+		t.Fatalf("SecretIDByName() error = %v", err)
+	}
+	if secretID != id {
+		t.Errorf("SecretIDByName() = %d, want %d", secretID, id)
+	}
+	if got := f.requestCount("GET /api/v1/secrets"); got != 2 {
+		t.Errorf("search requests = %d, want 2", got)
+	}
+}
+
+func TestSecretIDBySearch(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, map[string]string{"machine": "web01.example.com"})
+	f.addSecret("api", 0, loginTemplateID, map[string]string{"machine": "api01.example.com"})
+	client := newTestClient(t, f)
+
+	secretID, err := client.SecretIDBySearch(context.Background(), "web01")
+	if err != nil {
+		t.Fatalf("SecretIDBySearch() error = %v", err)
+	}
+	if secretID != id {
+		t.Errorf("SecretIDBySearch() = %d, want %d", secretID, id)
+	}
+
+	_, err = client.SecretIDBySearch(context.Background(), "example.com")
+	if err == nil || !strings.Contains(err.Error(), "2 secrets matching 'example.com' were found") {
+		t.Errorf("SecretIDBySearch() error = %v, want an ambiguous match", err)
+	}
+}
+
+func TestSecretTemplateByName(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newTestClient(t, f)
+
+	template, err := client.SecretTemplateByName(context.Background(), "test login")
+	if err != nil {
+		t.Fatalf("SecretTemplateByName() error = %v", err)
+	}
+	if template.ID != loginTemplateID || len(template.Fields) != 4 {
+		t.Errorf("SecretTemplateByName() = %d with %d fields, want %d with 4", template.ID, len(template.Fields), loginTemplateID)
+	}
+
+	// A partial name matches in the search, but not exactly
+	if _, err := client.SecretTemplateByName(context.Background(), "Test"); err == nil {
+		t.Error("SecretTemplateByName() with a partial name succeeded, want an error")
+	}
+}
+
+func TestFolderByPath(t *testing.T) {
+	f := newFakeSecretServer(t)
+	parent := f.addFolder("Apps", rootFolderID)
+	child := f.addFolder("Web", parent)
+	f.addFolder("Web", rootFolderID)
+	client := newTestClient(t, f)
+
+	folder, err := client.FolderByPath(context.Background(), "apps/web")
+	if err != nil {
+		t.Fatalf("FolderByPath() error = %v", err)
+	}
+	if folder.ID != child {
+		t.Errorf("FolderByPath() ID = %d, want %d", folder.ID, child)
+	}
+
+	if _, err := client.FolderByPath(context.Background(), `\`); err == nil {
+		t.Error("FolderByPath() of the root succeeded, want an error")
+	}
+}
+
+func TestFolderByPathReadsAllPages(t *testing.T) {
+	f := newFakeSecretServer(t)
+	for i := range secretSearchPageSize + 10 {
+		f.addFolder(fmt.Sprintf("Web-%03d", i), rootFolderID)
+	}
+	child := f.addFolder("Web", f.addFolder("Apps", rootFolderID))
+	client := newTestClient(t, f)
+
+	folder, err := client.FolderByPath(context.Background(), `\Apps\Web`)
+	if err != nil {
+		t.Fatalf("FolderByPath() error = %v", err)
+	}
+	if folder.ID != child {
+		t.Errorf("FolderByPath() ID = %d, want %d", folder.ID, child)
+	}
+	if got := f.requestCount("GET /api/v1/folders"); got != 2 {
+		t.Errorf("search requests = %d, want 2", got)
+	}
+}
+
+func TestHasStatus(t *testing.T) {
+	f := newFakeSecretServer(t)
+	client := newTestClient(t, f)
+	f.failNext("GET /api/v1/folders/1", http.StatusForbidden)
+
+	_, err := client.Folder(context.Background(), 1)
+	if !hasStatus(err, http.StatusForbidden) || isNotFound(err) || isUnauthorized(err) {
+		t.Errorf("Folder() error = %v, want only a 403", err)
+	}
+}
@@ -0,0 +1,124 @@
+package delinea
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// TSSFolderDataSource defines the data source implementation
+type TSSFolderDataSource struct {
+	client *Client // Shared provider client
+}
+
+// TSSFolderDataSourceModel defines the state structure for the data source
+type TSSFolderDataSourceModel struct {
+	ID                  types.Int64  `tfsdk:"id"`
+	Name                types.String `tfsdk:"name"`
+	ParentFolderID      types.Int64  `tfsdk:"parent_folder_id"`
+	InheritPermissions  types.Bool   `tfsdk:"inherit_permissions"`
+	InheritSecretPolicy types.Bool   `tfsdk:"inherit_secret_policy"`
+	SecretPolicyID      types.Int64  `tfsdk:"secret_policy_id"`
+	FolderPath          types.String `tfsdk:"folder_path"`
+}
+
+// Metadata provides the data source type name
+func (d *TSSFolderDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "tss_folder"
+}
+
+// Schema defines the schema for the data source
+func (d *TSSFolderDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"folder_path": schema.StringAttribute{
+				Required:    true,
+				Description: "The path of the folder to look up, e.g. '\\Parent\\Child' or 'Parent/Child'.",
+			},
+			"id": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The ID of the folder.",
+			},
+			"name": schema.StringAttribute{
+				Computed:    true,
+				Description: "The name of the folder.",
+			},
+			"parent_folder_id": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The ID of the parent folder, or -1 for a top-level folder.",
+			},
+			"inherit_permissions": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the folder inherits the permissions of its parent folder.",
+			},
+			"inherit_secret_policy": schema.BoolAttribute{
+				Computed:    true,
+				Description: "Whether the folder inherits the secret policy of its parent folder.",
+			},
+			"secret_policy_id": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The ID of the secret policy of the folder, if it has one.",
+			},
+		},
+	}
+}
+
+// Configure initializes the data source with the provider configuration
+func (d *TSSFolderDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*Client)
+	if !ok || client == nil {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
+		return
+	}
+
+	d.client = client
+}
+
+// Read looks up the folder by its path
+func (d *TSSFolderDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	ctx = withLogging(ctx)
+
+	var state TSSFolderDataSourceModel
+
+	// Read the configuration from the request
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if d.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	tflog.Debug(ctx, "Getting folder", map[string]interface{}{"folder_path": state.FolderPath.ValueString()})
+
+	folder, err := d.client.FolderByPath(ctx, state.FolderPath.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("folder_path"), "Folder Lookup Error", err.Error())
+		return
+	}
+
+	// The configured path is kept as written; everything else comes from the server
+	flattened := flattenFolder(folder)
+	state.ID = flattened.ID
+	state.Name = flattened.Name
+	state.ParentFolderID = flattened.ParentFolderID
+	state.InheritPermissions = flattened.InheritPermissions
+	state.InheritSecretPolicy = flattened.InheritSecretPolicy
+	state.SecretPolicyID = flattened.SecretPolicyID
+
+	// Set the state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
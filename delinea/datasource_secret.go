@@ -5,17 +5,41 @@ import (
 	"fmt"
 	"strconv"
 
-	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // TSSSecretDataSource defines the data source implementation
 type TSSSecretDataSource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
+// TSSSecretDataSourceModel defines the state structure for the data source
+type TSSSecretDataSourceModel struct {
+	SecretID       types.String `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	FolderPath     types.String `tfsdk:"folder_path"`
+	Search         types.String `tfsdk:"search"`
+	Field          types.String `tfsdk:"field"`
+	Fields         types.List   `tfsdk:"fields"`
+	AllFields      types.Bool   `tfsdk:"all_fields"`
+	Comment        types.String `tfsdk:"comment"`
+	TicketNumber   types.String `tfsdk:"ticket_number"`
+	TicketSystemID types.Int64  `tfsdk:"ticket_system_id"`
+	SecretValue    types.String `tfsdk:"value"`
+	Values         types.Map    `tfsdk:"values"`
+}
+
+// Ensure the data source implementation satisfies the config validator interface
+var _ datasource.DataSourceWithConfigValidators = &TSSSecretDataSource{}
+
 // Metadata provides the data source type name
 func (d *TSSSecretDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = "tss_secret"
@@ -26,22 +50,84 @@ func (d *TSSSecretDataSource) Schema(ctx context.Context, req datasource.SchemaR
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				Required:    true,
-				Description: "The ID of the secret to retrieve.",
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of the secret to retrieve. Conflicts with 'name' and 'search'; set to the ID of the found secret otherwise.",
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Description: "The name of the secret to retrieve. Exactly one secret must have this name. Conflicts with 'id' and 'search'.",
+			},
+			"folder_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "The path of the folder containing the secret named by 'name', e.g. '\\Parent\\Child' or 'Parent/Child'.",
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("name")),
+				},
+			},
+			"search": schema.StringAttribute{
+				Optional:    true,
+				Description: "A search term that must match exactly one secret. Conflicts with 'id' and 'name'.",
 			},
 			"field": schema.StringAttribute{
-				Required:    true,
-				Description: "The field to extract from the secret.",
+				Optional:    true,
+				Description: "The field to extract from the secret. Conflicts with 'fields' and 'all_fields'.",
+			},
+			"fields": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "The names or slugs of the fields to extract from the secret into 'values'. Conflicts with 'field' and 'all_fields'.",
+			},
+			"all_fields": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to extract every field of the secret into 'values', keyed by field slug. Conflicts with 'field' and 'fields'.",
+			},
+			"comment": schema.StringAttribute{
+				Optional:    true,
+				Description: "A comment recorded in the audit log of the secret views. Required by secrets that require a comment to be viewed.",
+			},
+			"ticket_number": schema.StringAttribute{
+				Optional:    true,
+				Description: "A ticket number recorded in the audit log of the secret views. Required by secrets that require a ticket number to be viewed.",
+			},
+			"ticket_system_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The ID of the ticket system that 'ticket_number' belongs to, if it is not the default one.",
+				Validators: []validator.Int64{
+					int64validator.AlsoRequires(path.MatchRoot("ticket_number")),
+				},
 			},
 			"value": schema.StringAttribute{
 				Computed:    true,
 				Sensitive:   true,
-				Description: "The value of the requested field from the secret.",
+				Description: "The value of the requested field from the secret. Only set when 'field' is used.",
+			},
+			"values": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The values of the requested fields, keyed by the requested field name or, with 'all_fields', by field slug.",
 			},
 		},
 	}
 }
 
+// ConfigValidators ensures exactly one way of identifying the secret and of selecting fields is used
+func (d *TSSSecretDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("name"),
+			path.MatchRoot("search"),
+		),
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("field"),
+			path.MatchRoot("fields"),
+			path.MatchRoot("all_fields"),
+		),
+	}
+}
+
 // Configure initializes the data source with the provider configuration
 func (d *TSSSecretDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 	if req.ProviderData == nil {
@@ -50,29 +136,20 @@ func (d *TSSSecretDataSource) Configure(ctx context.Context, req datasource.Conf
 		return
 	}
 
-	// Log the received ProviderData
-	fmt.Printf("DEBUG: ProviderData received in Configure")
-
-	config, ok := req.ProviderData.(*server.Configuration)
-	if !ok || config == nil {
-		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider configuration")
+	client, ok := req.ProviderData.(*Client)
+	if !ok || client == nil {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
 		return
 	}
 
-	// Log the successfully retrieved configuration
-	fmt.Printf("DEBUG: Successfully retrieved provider configuration")
-
-	d.clientConfig = config
+	d.client = client
 }
 
 // Read retrieves the data for the data source
 func (d *TSSSecretDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	// Define the state structure
-	var state struct {
-		SecretID    types.String `tfsdk:"id"`
-		Field       types.String `tfsdk:"field"`
-		SecretValue types.String `tfsdk:"value"`
-	}
+	ctx = withLogging(ctx)
+
+	var state TSSSecretDataSourceModel
 
 	// Read the configuration from the request
 	diags := req.Config.Get(ctx, &state)
@@ -81,49 +158,103 @@ func (d *TSSSecretDataSource) Read(ctx context.Context, req datasource.ReadReque
 		return
 	}
 
-	// Ensure the client configuration is set
-	if d.clientConfig == nil {
+	// Ensure the client is configured
+	if d.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	client, err := server.New(*d.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
-		return
-	}
+	tflog.Debug(ctx, "Getting secret", map[string]interface{}{
+		"secret_id": state.SecretID.ValueString(),
+		"name":      state.Name.ValueString(),
+		"search":    state.Search.ValueString(),
+	})
 
-	// Convert SecretID to int
-	secretID, err := strconv.Atoi(state.SecretID.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError("Invalid Secret ID", "Secret ID must be an integer")
+	// Fetch the secret once, whichever fields are requested
+	secret, diags := secretLookup{
+		ID:         state.SecretID,
+		Name:       state.Name,
+		FolderPath: state.FolderPath,
+		Search:     state.Search,
+		Access:     newSecretAccess(state.Comment, state.TicketNumber, state.TicketSystemID),
+	}.fetch(ctx, d.client)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
+	ctx = maskSecretValues(ctx, secret)
 
-	fmt.Printf("[DEBUG] getting secret with id %d", secretID)
+	secretID := secret.ID
+	state.SecretID = types.StringValue(strconv.Itoa(secretID))
 
-	// Fetch the secret
-	secret, err := client.Secret(secretID)
-	if err != nil {
-		resp.Diagnostics.AddError("Secret Fetch Error", fmt.Sprintf("Failed to fetch secret: %s", err))
-		return
-	}
+	values := map[string]string{}
+
+	switch {
+	case !state.Field.IsNull():
+		// Get the field name dynamically
+		fieldName := state.Field.ValueString()
+
+		tflog.Debug(ctx, "Using field of secret", map[string]interface{}{"field": fieldName, "secret_id": secretID})
+
+		// Extract the secret value
+		fieldValue, ok := secret.Field(fieldName)
+		if !ok {
+			resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("The secret does not contain the field '%s'", fieldName))
+			return
+		}
 
-	// Get the field name dynamically
-	fieldName := state.Field.ValueString()
+		// Set the secret value in the state
+		state.SecretValue = types.StringValue(fieldValue)
+		values[fieldName] = fieldValue
 
-	fmt.Printf("[DEBUG] using '%s' field of secret with id %d", fieldName, secretID)
+	case !state.Fields.IsNull():
+		var fieldNames []string
+		resp.Diagnostics.Append(state.Fields.ElementsAs(ctx, &fieldNames, false)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 
-	// Extract the secret value
-	fieldValue, ok := secret.Field(fieldName)
-	if !ok {
-		resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("The secret does not contain the field '%s'", fieldName))
+		tflog.Debug(ctx, "Using fields of secret", map[string]interface{}{"fields": fieldNames, "secret_id": secretID})
+
+		// Key every value by the name it was requested with
+		for _, fieldName := range fieldNames {
+			fieldValue, ok := secret.Field(fieldName)
+			if !ok {
+				resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("The secret does not contain the field '%s'", fieldName))
+				continue
+			}
+			values[fieldName] = fieldValue
+		}
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		state.SecretValue = types.StringNull()
+
+	case state.AllFields.ValueBool():
+		tflog.Debug(ctx, "Using all fields of secret", map[string]interface{}{"secret_id": secretID})
+
+		// Key every value by the field slug, falling back to the name for fields without one
+		for _, field := range secret.Fields {
+			key := field.Slug
+			if key == "" {
+				key = field.FieldName
+			}
+			values[key] = field.ItemValue
+		}
+
+		state.SecretValue = types.StringNull()
+
+	default:
+		resp.Diagnostics.AddError("Missing Field Selection", "One of 'field', 'fields' or 'all_fields = true' must be set.")
 		return
 	}
 
-	// Set the secret value in the state
-	state.SecretValue = types.StringValue(fieldValue)
+	state.Values, diags = types.MapValueFrom(ctx, types.StringType, values)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Set the state
 	diags = resp.State.Set(ctx, &state)
@@ -0,0 +1,207 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// TSSSecretTemplateDataSource defines the data source implementation
+type TSSSecretTemplateDataSource struct {
+	client *Client // Shared provider client
+}
+
+// TSSSecretTemplateDataSourceModel defines the state structure for the data source
+type TSSSecretTemplateDataSourceModel struct {
+	ID     types.Int64                `tfsdk:"id"`
+	Name   types.String               `tfsdk:"name"`
+	Fields []SecretTemplateFieldModel `tfsdk:"fields"`
+}
+
+// SecretTemplateFieldModel is a field of a secret template
+type SecretTemplateFieldModel struct {
+	ID          types.Int64  `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Slug        types.String `tfsdk:"slug"`
+	DisplayName types.String `tfsdk:"display_name"`
+	Description types.String `tfsdk:"description"`
+	IsFile      types.Bool   `tfsdk:"is_file"`
+	IsPassword  types.Bool   `tfsdk:"is_password"`
+	IsNotes     types.Bool   `tfsdk:"is_notes"`
+	IsList      types.Bool   `tfsdk:"is_list"`
+	IsURL       types.Bool   `tfsdk:"is_url"`
+	IsRequired  types.Bool   `tfsdk:"is_required"`
+}
+
+// Ensure the data source implementation satisfies the config validator interface
+var _ datasource.DataSourceWithConfigValidators = &TSSSecretTemplateDataSource{}
+
+// Metadata provides the data source type name
+func (d *TSSSecretTemplateDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = "tss_secret_template"
+}
+
+// Schema defines the schema for the data source
+func (d *TSSSecretTemplateDataSource) Schema(ctx context.Context, req datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of the secret template. Conflicts with 'name'; set to the ID of the found template otherwise.",
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The name of the secret template. Exactly one template must have this name. Conflicts with 'id'; set to the name of the found template otherwise.",
+			},
+			"fields": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "The fields of the secret template, in template order.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.Int64Attribute{
+							Computed:    true,
+							Description: "The ID of the template field.",
+						},
+						"name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The name of the field, as used in the 'fieldname' of a tss_resource_secret.",
+						},
+						"slug": schema.StringAttribute{
+							Computed:    true,
+							Description: "The slug of the field.",
+						},
+						"display_name": schema.StringAttribute{
+							Computed:    true,
+							Description: "The display name of the field.",
+						},
+						"description": schema.StringAttribute{
+							Computed:    true,
+							Description: "The description of the field.",
+						},
+						"is_file": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the field holds a file attachment.",
+						},
+						"is_password": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the field holds a password.",
+						},
+						"is_notes": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the field is a multi-line notes field.",
+						},
+						"is_list": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the field is a list field.",
+						},
+						"is_url": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the field holds a URL.",
+						},
+						"is_required": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether secrets created from the template must set the field.",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ConfigValidators ensures the template is identified either by ID or by name
+func (d *TSSSecretTemplateDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("name"),
+		),
+	}
+}
+
+// Configure initializes the data source with the provider configuration
+func (d *TSSSecretTemplateDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*Client)
+	if !ok || client == nil {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
+		return
+	}
+
+	d.client = client
+}
+
+// Read retrieves the secret template and its fields
+func (d *TSSSecretTemplateDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	ctx = withLogging(ctx)
+
+	var state TSSSecretTemplateDataSourceModel
+
+	// Read the configuration from the request
+	diags := req.Config.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if d.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	var template *server.SecretTemplate
+	var err error
+	if !state.Name.IsNull() {
+		tflog.Debug(ctx, "Getting secret template", map[string]interface{}{"name": state.Name.ValueString()})
+
+		template, err = d.client.SecretTemplateByName(ctx, state.Name.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("name"), "Secret Template Lookup Error", err.Error())
+			return
+		}
+	} else {
+		tflog.Debug(ctx, "Getting secret template", map[string]interface{}{"template_id": state.ID.ValueInt64()})
+
+		template, err = d.client.SecretTemplate(ctx, int(state.ID.ValueInt64()))
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root("id"), "Secret Template Fetch Error", fmt.Sprintf("Failed to fetch secret template: %s", err))
+			return
+		}
+	}
+
+	state.ID = types.Int64Value(int64(template.ID))
+	state.Name = types.StringValue(template.Name)
+	state.Fields = make([]SecretTemplateFieldModel, len(template.Fields))
+	for i, field := range template.Fields {
+		state.Fields[i] = SecretTemplateFieldModel{
+			ID:          types.Int64Value(int64(field.SecretTemplateFieldID)),
+			Name:        types.StringValue(field.Name),
+			Slug:        types.StringValue(field.FieldSlugName),
+			DisplayName: types.StringValue(field.DisplayName),
+			Description: types.StringValue(field.Description),
+			IsFile:      types.BoolValue(field.IsFile),
+			IsPassword:  types.BoolValue(field.IsPassword),
+			IsNotes:     types.BoolValue(field.IsNotes),
+			IsList:      types.BoolValue(field.IsList),
+			IsURL:       types.BoolValue(field.IsUrl),
+			IsRequired:  types.BoolValue(field.IsRequired),
+		}
+	}
+
+	// Set the state
+	diags = resp.State.Set(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+}
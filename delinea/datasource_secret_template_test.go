@@ -0,0 +1,49 @@
+package delinea
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceSecretTemplate(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secret_template" "by_id" {
+  id = %d
+}
+
+data "tss_secret_template" "by_name" {
+  name = "test certificate"
+}
+`, loginTemplateID),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_id", "name", "Test Login"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_id", "fields.#", "4"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_id", "fields.2.slug", "password"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_id", "fields.2.is_password", "true"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_id", "fields.1.is_required", "true"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_name", "id", strconv.Itoa(fileTemplateID)),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_name", "name", "Test Certificate"),
+					resource.TestCheckResourceAttr("data.tss_secret_template.by_name", "fields.1.is_file", "true"),
+				),
+			},
+			{
+				Config: f.providerConfig() + `
+data "tss_secret_template" "test" {
+  name = "Unix Account"
+}
+`,
+				ExpectError: regexp.MustCompile(`no secret template named 'Unix Account' was found`),
+			},
+		},
+	})
+}
@@ -0,0 +1,144 @@
+package delinea
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccDataSourceSecret(t *testing.T) {
+	f := newFakeSecretServer(t)
+	folder := f.addFolder("Prod", rootFolderID)
+	id := f.addSecret("web", folder, loginTemplateID, map[string]string{
+		"machine":  "web01.example.com",
+		"username": "admin",
+		"password": "s3cret!",
+	})
+	f.addSecret("web", rootFolderID, loginTemplateID, nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secret" "by_id" {
+  id    = "%d"
+  field = "password"
+}
+
+data "tss_secret" "by_name" {
+  name        = "web"
+  folder_path = "/Prod"
+  fields      = ["Username", "machine"]
+}
+
+data "tss_secret" "by_search" {
+  search     = "web01"
+  all_fields = true
+}
+`, id),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secret.by_id", "value", "s3cret!"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_id", "values.password", "s3cret!"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_name", "id", strconv.Itoa(id)),
+					resource.TestCheckNoResourceAttr("data.tss_secret.by_name", "value"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_name", "values.Username", "admin"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_name", "values.machine", "web01.example.com"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_search", "id", strconv.Itoa(id)),
+					resource.TestCheckResourceAttr("data.tss_secret.by_search", "values.%", "4"),
+					resource.TestCheckResourceAttr("data.tss_secret.by_search", "values.notes", ""),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceSecret_errors(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", rootFolderID, loginTemplateID, nil)
+	f.addSecret("web", rootFolderID, loginTemplateID, nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + `
+data "tss_secret" "test" {
+  name  = "web"
+  field = "password"
+}
+`,
+				ExpectError: regexp.MustCompile(`2 secrets named 'web' were found`),
+			},
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secret" "test" {
+  id    = "%d"
+  field = "hostname"
+}
+`, id),
+				ExpectError: regexp.MustCompile(`The secret does not contain the field 'hostname'`),
+			},
+			{
+				Config: f.providerConfig() + `
+data "tss_secret" "test" {
+  id     = "1"
+  search = "web"
+  field  = "password"
+}
+`,
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceSecret_comment(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("admin", rootFolderID, loginTemplateID, map[string]string{"password": "s3cret!"})
+	f.requireComment(id)
+
+	config := func(access string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+data "tss_secret" "test" {
+  id    = "%d"
+  field = "password"
+  %s
+}
+`, id, access)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config(""),
+				ExpectError: regexp.MustCompile(`The secret requires a\s+comment`),
+			},
+			{
+				Config:      config(`ticket_system_id = 2`),
+				ExpectError: regexp.MustCompile(`Invalid Attribute Combination`),
+			},
+			{
+				Config: config(`
+  comment          = "terraform plan"
+  ticket_number    = "CHG-42"
+  ticket_system_id = 2`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secret.test", "value", "s3cret!"),
+					func(*terraform.State) error {
+						want := secretAccess{Comment: "terraform plan", TicketNumber: "CHG-42", TicketSystemID: 2}
+						if views := f.viewJustifications(id); len(views) == 0 || views[0] != want {
+							return fmt.Errorf("view justifications = %+v, want %+v", views, want)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
@@ -2,19 +2,42 @@ package delinea
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/datasourcevalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // TSSSecretsDataSource defines the data source implementation
 type TSSSecretsDataSource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
+// TSSSecretsDataSourceModel defines the state structure for the data source
+type TSSSecretsDataSourceModel struct {
+	IDs            []types.Int64           `tfsdk:"ids"`
+	Names          []types.String          `tfsdk:"names"`
+	FolderPath     types.String            `tfsdk:"folder_path"`
+	Searches       []types.String          `tfsdk:"searches"`
+	Field          types.String            `tfsdk:"field"`
+	Comment        types.String            `tfsdk:"comment"`
+	TicketNumber   types.String            `tfsdk:"ticket_number"`
+	TicketSystemID types.Int64             `tfsdk:"ticket_system_id"`
+	OnError        types.String            `tfsdk:"on_error"`
+	Secrets        []SecretModel           `tfsdk:"secrets"`
+	ByID           map[string]types.String `tfsdk:"by_id"`
+	Errors         map[string]types.String `tfsdk:"errors"`
+}
+
+// Ensure the data source implementation satisfies the config validator interface
+var _ datasource.DataSourceWithConfigValidators = &TSSSecretsDataSource{}
+
 // Metadata provides the data source type name
 func (d *TSSSecretsDataSource) Metadata(ctx context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
 	resp.TypeName = "tss_secrets"
@@ -26,13 +49,53 @@ func (d *TSSSecretsDataSource) Schema(ctx context.Context, req datasource.Schema
 		Attributes: map[string]schema.Attribute{
 			"ids": schema.ListAttribute{
 				ElementType: types.Int64Type,
-				Required:    true,
-				Description: "A list of IDs of the secrets",
+				Optional:    true,
+				Computed:    true,
+				Description: "A list of IDs of the secrets. Conflicts with 'names' and 'searches'; set to the IDs of the found secrets otherwise",
+			},
+			"names": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "A list of secret names, each of which must match exactly one secret. Conflicts with 'ids' and 'searches'",
+			},
+			"folder_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "The path of the folder containing the secrets named by 'names', e.g. '\\Parent\\Child' or 'Parent/Child'",
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("names")),
+				},
+			},
+			"searches": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "A list of search terms, each of which must match exactly one secret. Conflicts with 'ids' and 'names'",
 			},
 			"field": schema.StringAttribute{
 				Required:    true,
 				Description: "The field to extract from the secrets",
 			},
+			"comment": schema.StringAttribute{
+				Optional:    true,
+				Description: "A comment recorded in the audit log of the secret views. Required by secrets that require a comment to be viewed.",
+			},
+			"ticket_number": schema.StringAttribute{
+				Optional:    true,
+				Description: "A ticket number recorded in the audit log of the secret views. Required by secrets that require a ticket number to be viewed.",
+			},
+			"ticket_system_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The ID of the ticket system that 'ticket_number' belongs to, if it is not the default one.",
+				Validators: []validator.Int64{
+					int64validator.AlsoRequires(path.MatchRoot("ticket_number")),
+				},
+			},
+			"on_error": schema.StringAttribute{
+				Optional:    true,
+				Description: "What to do when a secret cannot be fetched or does not have the field: 'fail' fails, 'skip' leaves the secret out of 'secrets' and 'null' keeps its position with a null value. Failures are listed in 'errors' unless the read fails. When not set, secrets that cannot be fetched are skipped with a warning and a secret without the field fails, as in earlier versions",
+				Validators: []validator.String{
+					stringvalidator.OneOf(onErrorValues...),
+				},
+			},
 			"secrets": schema.ListNestedAttribute{
 				Computed:    true,
 				Description: "A list of secrets with their field values",
@@ -50,10 +113,32 @@ func (d *TSSSecretsDataSource) Schema(ctx context.Context, req datasource.Schema
 					},
 				},
 			},
+			"by_id": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Sensitive:   true,
+				Description: "The field values of the fetched secrets by secret ID, which do not depend on the order of the results",
+			},
+			"errors": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The reasons why secrets could not be fetched, by secret ID, or by name or search term when the lookup itself failed",
+			},
 		},
 	}
 }
 
+// ConfigValidators ensures exactly one way of identifying the secrets is used
+func (d *TSSSecretsDataSource) ConfigValidators(ctx context.Context) []datasource.ConfigValidator {
+	return []datasource.ConfigValidator{
+		datasourcevalidator.ExactlyOneOf(
+			path.MatchRoot("ids"),
+			path.MatchRoot("names"),
+			path.MatchRoot("searches"),
+		),
+	}
+}
+
 // Configure initializes the data source with the provider configuration
 func (d *TSSSecretsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 	if req.ProviderData == nil {
@@ -62,33 +147,21 @@ func (d *TSSSecretsDataSource) Configure(ctx context.Context, req datasource.Con
 		return
 	}
 
-	// Log the received ProviderData
-	fmt.Printf("DEBUG: ProviderData received in Configure")
-
-	// Retrieve the provider configuration
-	config, ok := req.ProviderData.(*server.Configuration)
-	if !ok {
-		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider configuration")
+	// Retrieve the shared provider client
+	client, ok := req.ProviderData.(*Client)
+	if !ok || client == nil {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
 		return
 	}
 
-	// Log the successfully retrieved configuration
-	fmt.Printf("DEBUG: Successfully retrieved provider configuration")
-
-	// Store the provider configuration in the data source
-	d.clientConfig = config
-	fmt.Println("DEBUG: Provider configuration stored in clientConfig")
+	// Store the shared client in the data source
+	d.client = client
 }
 
 func (d *TSSSecretsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
-	var state struct {
-		IDs     []types.Int64 `tfsdk:"ids"`
-		Field   types.String  `tfsdk:"field"`
-		Secrets []struct {
-			ID    types.Int64  `tfsdk:"id"`
-			Value types.String `tfsdk:"value"`
-		} `tfsdk:"secrets"`
-	}
+	ctx = withLogging(ctx)
+
+	var state TSSSecretsDataSourceModel
 
 	// Read the configuration
 	diags := req.Config.Get(ctx, &state)
@@ -97,61 +170,40 @@ func (d *TSSSecretsDataSource) Read(ctx context.Context, req datasource.ReadRequ
 		return
 	}
 
-	// Ensure the client configuration is set
-	if d.clientConfig == nil {
+	// Ensure the client is configured
+	if d.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	secretsClient, err := server.New(*d.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
+	tflog.Debug(ctx, "Getting secrets", map[string]interface{}{
+		"secret_ids": len(state.IDs),
+		"names":      len(state.Names),
+		"searches":   len(state.Searches),
+	})
+
+	// Fetch the secrets concurrently and extract the field; the results keep the order of the input
+	result, _, diags := secretsLookup{
+		IDs:        state.IDs,
+		Names:      state.Names,
+		FolderPath: state.FolderPath,
+		Searches:   state.Searches,
+		Access:     newSecretAccess(state.Comment, state.TicketNumber, state.TicketSystemID),
+	}.fetchField(ctx, d.client, state.Field.ValueString(), state.OnError.ValueString())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Fetch secrets
-	var results []struct {
-		ID    types.Int64  `tfsdk:"id"`
-		Value types.String `tfsdk:"value"`
-	}
-
-	for _, id := range state.IDs {
-		secretID := int(id.ValueInt64())
-
-		fmt.Printf("[DEBUG] getting secret with id %d", secretID)
-
-		// Fetch the secret
-		secret, err := secretsClient.Secret(secretID)
-		if err != nil {
-			resp.Diagnostics.AddWarning("Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret with ID %d: %s", secretID, err))
-			continue // Skip this ID and continue with the rest
-		}
-
-		// Get the field name dynamically
-		fieldName := state.Field.ValueString()
-
-		fmt.Printf("[DEBUG] using '%s' field of secret with id %d", fieldName, secretID)
-
-		// Extract the field value
-		fieldValue, ok := secret.Field(fieldName)
-		if !ok {
-			resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("The secret does not contain the field '%s'", fieldName))
-			continue
-		}
-
-		// Save the secret value in the state
-		results = append(results, struct {
-			ID    types.Int64  `tfsdk:"id"`
-			Value types.String `tfsdk:"value"`
-		}{
-			ID:    types.Int64Value(int64(secretID)),
-			Value: types.StringValue(fieldValue),
-		})
+	// Secrets looked up by name or search term report the IDs they resolved to
+	if state.IDs == nil {
+		state.IDs = result.IDs
 	}
 
 	// Set the state
-	state.Secrets = results
+	state.Secrets = result.Secrets
+	state.ByID = result.ByID
+	state.Errors = result.Errors
 	diags = resp.State.Set(ctx, &state)
 	resp.Diagnostics.Append(diags...)
 }
@@ -0,0 +1,216 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceSecrets(t *testing.T) {
+	f := newFakeSecretServer(t)
+	folder := f.addFolder("Prod", rootFolderID)
+	web := f.addSecret("web", folder, loginTemplateID, map[string]string{"username": "web-admin", "machine": "web01"})
+	db := f.addSecret("db", folder, loginTemplateID, map[string]string{"username": "db-admin", "machine": "db01"})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secrets" "by_ids" {
+  ids   = [%d, %d]
+  field = "username"
+}
+
+data "tss_secrets" "by_names" {
+  names       = ["db", "web"]
+  folder_path = "\\Prod"
+  field       = "Username"
+}
+
+data "tss_secrets" "by_searches" {
+  searches = ["web01"]
+  field    = "username"
+}
+`, web, db),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secrets.by_ids", "secrets.#", "2"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_ids", "secrets.0.id", strconv.Itoa(web)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_ids", "secrets.0.value", "web-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_ids", "secrets.1.id", strconv.Itoa(db)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_ids", "secrets.1.value", "db-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "secrets.0.id", strconv.Itoa(db)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "secrets.1.value", "web-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_searches", "secrets.#", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_searches", "secrets.0.value", "web-admin"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceSecrets_onError(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"username": "web-admin"})
+	config := func(onError string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+data "tss_secrets" "test" {
+  ids      = [9999, %d]
+  field    = "username"
+  %s
+}
+
+data "tss_secrets" "by_names" {
+  names    = ["cache", "web"]
+  field    = "username"
+  %[2]s
+}
+`, web, onError)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config(`on_error = "ignore"`),
+				ExpectError: regexp.MustCompile(`Invalid Attribute Value Match`),
+			},
+			{
+				// A secret that cannot be read is skipped by default, as before on_error existed
+				Config: config(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.#", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.0.value", "web-admin"),
+					resource.TestMatchResourceAttr("data.tss_secrets.test", "errors.9999", regexp.MustCompile(`^404`)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "ids.#", "1"),
+				),
+			},
+			{
+				Config:      config(`on_error = "fail"`),
+				ExpectError: regexp.MustCompile(`Failed to fetch secret 9999: 404`),
+			},
+			{
+				Config:      config(`on_error = "fail"`),
+				ExpectError: regexp.MustCompile(`no secret named 'cache' was found`),
+			},
+			{
+				Config: config(`on_error = "skip"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.#", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.0.id", strconv.Itoa(web)),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.0.value", "web-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "by_id.%", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "by_id."+strconv.Itoa(web), "web-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "errors.%", "1"),
+					resource.TestMatchResourceAttr("data.tss_secrets.test", "errors.9999", regexp.MustCompile(`^404`)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "ids.#", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "ids.0", strconv.Itoa(web)),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "errors.cache", "no secret named 'cache' was found"),
+				),
+			},
+			{
+				Config: config(`on_error = "null"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.#", "2"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.0.id", "9999"),
+					resource.TestCheckNoResourceAttr("data.tss_secrets.test", "secrets.0.value"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.1.value", "web-admin"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "by_id.%", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "errors.%", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "ids.#", "2"),
+					resource.TestCheckNoResourceAttr("data.tss_secrets.by_names", "ids.0"),
+					resource.TestCheckResourceAttr("data.tss_secrets.by_names", "secrets.1.value", "web-admin"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceSecrets_missingField(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"username": "web-admin"})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secrets" "test" {
+  ids   = [%d]
+  field = "api-key"
+}
+`, web),
+				ExpectError: regexp.MustCompile(`Field Not Found(.|\n)*The secret does not contain the field 'api-key'`),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceSecrets_missingID(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"username": "web-admin"})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// A secret that cannot be read is skipped with a warning
+				Config: f.providerConfig() + fmt.Sprintf(`
+data "tss_secrets" "test" {
+  ids      = [%d, 9999]
+  field    = "username"
+  on_error = "skip"
+}
+`, web),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.#", "1"),
+					resource.TestCheckResourceAttr("data.tss_secrets.test", "secrets.0.value", "web-admin"),
+				),
+			},
+		},
+	})
+}
+
+func TestSecretsLookupDefaultDiagnostics(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"username": "web-admin"})
+	client := newTestClient(t, f)
+
+	tests := []struct {
+		name            string
+		ids             []int
+		field           string
+		severity        diag.Severity
+		summary, detail string
+	}{
+		{"missing secret", []int{web, 9999}, "username", diag.SeverityWarning, "Secret Fetch Warning", "Failed to fetch secret with ID 9999: 404 Not Found"},
+		{"missing field", []int{web}, "api-key", diag.SeverityError, "Field Not Found", "The secret does not contain the field 'api-key'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []types.Int64
+			for _, id := range tt.ids {
+				ids = append(ids, types.Int64Value(int64(id)))
+			}
+
+			_, _, diags := secretsLookup{IDs: ids}.fetchField(context.Background(), client, tt.field, "")
+			if len(diags) != 1 {
+				t.Fatalf("fetchField() diagnostics = %v, want one", diags)
+			}
+			d := diags[0]
+			if d.Severity() != tt.severity || d.Summary() != tt.summary || !strings.HasPrefix(d.Detail(), tt.detail) {
+				t.Errorf("fetchField() diagnostic = %v %q: %q, want %v %q: %q", d.Severity(), d.Summary(), d.Detail(), tt.severity, tt.summary, tt.detail)
+			}
+			if _, ok := d.(diag.DiagnosticWithPath); ok {
+				t.Errorf("fetchField() diagnostic has an attribute path, want none")
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package delinea
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// defaultStateFileMode is the mode of state files that did not exist before, which
+// keeps them readable by their owner only
+const defaultStateFileMode fs.FileMode = 0600
+
+// backupSuffix is appended to the name of a state file to name its backup
+const backupSuffix = ".bak"
+
+// FileOptions are the options of the functions that encrypt and decrypt state files
+type FileOptions struct {
+	// KDF derives the key of newly encrypted files; DefaultKDF is used when it is not set
+	KDF KDFParams
+
+	// Backup keeps the previous content of a file in <file>.bak until the new content
+	// is written and verified. The backup stays in place if the verification fails.
+	Backup bool
+}
+
+// kdf returns the key derivation function of the options
+func (o FileOptions) kdf() KDFParams {
+	if o.KDF.Name == "" {
+		return DefaultKDF
+	}
+	return o.KDF
+}
+
+// replaceStateFile replaces a state file with the content produced by write. The
+// previous content is kept in a backup if the options ask for it, until the new file is
+// read back unchanged and verify accepts it. The source that write reads is closed
+// before the file is replaced, since Windows cannot replace a file that is open.
+func replaceStateFile(stateFile string, src io.Closer, opts FileOptions, write func(w io.Writer) error, verify func(r io.Reader) error) error {
+	mode := fileMode(stateFile)
+	backupFile := stateFile + backupSuffix
+	if opts.Backup {
+		err := writeFileAtomic(backupFile, mode, func(w io.Writer) error {
+			return copyFile(w, stateFile)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write backup: %v", err)
+		}
+	}
+
+	written := sha256.New()
+	err := writeFileAtomic(stateFile, mode, func(w io.Writer) error {
+		err := write(io.MultiWriter(w, written))
+		src.Close()
+		return err
+	})
+	if err != nil || !opts.Backup {
+		return err
+	}
+
+	if err := verifyFile(stateFile, written.Sum(nil), verify); err != nil {
+		return fmt.Errorf("failed to verify %s, the previous content is kept in %s: %v", stateFile, backupFile, err)
+	}
+	if err := os.Remove(backupFile); err != nil {
+		return fmt.Errorf("failed to remove backup: %v", err)
+	}
+	return nil
+}
+
+// verifyFile reads a file back and checks that it has the written checksum and that
+// verify accepts its content
+func verifyFile(path string, sum []byte, verify func(r io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	read := sha256.New()
+	r := io.TeeReader(f, read)
+	if verify != nil {
+		if err := verify(r); err != nil {
+			return err
+		}
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(read.Sum(nil), sum) {
+		return errors.New("the file content differs from what was written")
+	}
+	return nil
+}
+
+// decryptsTo returns a check that encrypted content decrypts to the plaintext with the
+// given checksum
+func decryptsTo(passphrase string, plaintext hash.Hash) func(io.Reader) error {
+	return func(r io.Reader) error {
+		decrypted, err := NewDecryptReader(r, passphrase)
+		if err != nil {
+			return err
+		}
+		sum := sha256.New()
+		if _, err := io.Copy(sum, decrypted); err != nil {
+			return err
+		}
+		if !bytes.Equal(sum.Sum(nil), plaintext.Sum(nil)) {
+			return errors.New("the encrypted content does not decrypt to the original state")
+		}
+		return nil
+	}
+}
+
+// writeFileAtomic writes the content produced by write to a temporary file in the
+// directory of path, flushes it to disk and renames it over path, so that a crash or an
+// error leaves either the old or the new content in place, never a partial file
+func writeFileAtomic(path string, mode fs.FileMode, write func(w io.Writer) error) (err error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(mode); err != nil && runtime.GOOS != "windows" {
+		return fmt.Errorf("failed to set file mode: %v", err)
+	}
+	bw := bufio.NewWriterSize(tmp, 64*1024)
+	if err := write(bw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to flush temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+
+	// Flush the rename as well; directories cannot be synced on Windows
+	if runtime.GOOS != "windows" {
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
+
+// copyFile writes the content of a file to w
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// openStateFile opens a state file for reading and detects its format from its start
+func openStateFile(path string) (*os.File, *bufio.Reader, StateFormat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, FormatPlaintext, err
+	}
+
+	br := bufio.NewReader(f)
+	format, _, err := PeekFormat(br)
+	if err != nil {
+		f.Close()
+		return nil, nil, FormatPlaintext, err
+	}
+	return f, br, format, nil
+}
+
+// fileMode returns the permissions of an existing file, or the default mode of state
+// files if it does not exist
+func fileMode(path string) fs.FileMode {
+	info, err := os.Stat(path)
+	if err != nil {
+		return defaultStateFileMode
+	}
+	return info.Mode().Perm()
+}
@@ -0,0 +1,134 @@
+package delinea
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// dirEntries returns the names of the files in dir
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names
+}
+
+// writeString returns a write function for writeFileAtomic that writes s
+func writeString(s string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestEncryptFileKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+
+	stateFile := writeStateFile(t, []byte(testState))
+	if err := os.Chmod(stateFile, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := FileOptions{KDF: testKDF, Backup: true}
+	if err := EncryptFileWithOptions("passphrase", stateFile, opts); err != nil {
+		t.Fatalf("EncryptFileWithOptions() error = %v", err)
+	}
+	if err := RekeyFile("passphrase", "new", stateFile, opts); err != nil {
+		t.Fatalf("RekeyFile() error = %v", err)
+	}
+	if err := DecryptFileWithOptions("new", stateFile, opts); err != nil {
+		t.Fatalf("DecryptFileWithOptions() error = %v", err)
+	}
+
+	info, err := os.Stat(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %v, want -rw-r-----", info.Mode().Perm())
+	}
+	if string(readStateFile(t, stateFile)) != testState {
+		t.Errorf("state file = %q, want the original state", readStateFile(t, stateFile))
+	}
+
+	// Neither temporary files nor verified backups are left behind
+	if names := dirEntries(t, filepath.Dir(stateFile)); len(names) != 1 {
+		t.Errorf("files = %v, want only the state file", names)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+
+	if err := writeFileAtomic(path, fileMode(path), writeString(testState)); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+	if string(readStateFile(t, path)) != testState {
+		t.Errorf("file = %q, want the state", readStateFile(t, path))
+	}
+	if runtime.GOOS != "windows" {
+		if mode := fileMode(path); mode != defaultStateFileMode {
+			t.Errorf("mode of a new file = %v, want %v", mode, defaultStateFileMode)
+		}
+	}
+
+	// A failed write leaves the file as it was and no temporary file behind
+	writeErr := errors.New("write failed")
+	err := writeFileAtomic(path, defaultStateFileMode, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Errorf("writeFileAtomic() error = %v, want the write error", err)
+	}
+	if string(readStateFile(t, path)) != testState {
+		t.Errorf("file = %q after a failed write, want it unchanged", readStateFile(t, path))
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing", "terraform.tfstate")
+	if err := writeFileAtomic(missing, defaultStateFileMode, writeString(testState)); err == nil {
+		t.Error("writeFileAtomic() into a missing directory succeeded")
+	}
+	if names := dirEntries(t, filepath.Dir(path)); len(names) != 1 {
+		t.Errorf("files = %v, want only the written file", names)
+	}
+}
+
+func TestReplaceStateFileBackup(t *testing.T) {
+	stateFile := writeStateFile(t, []byte("previous"))
+
+	verifyErr := errors.New("verification failed")
+	err := replaceStateFile(stateFile, io.NopCloser(nil), FileOptions{Backup: true}, writeString("next"), func(io.Reader) error {
+		return verifyErr
+	})
+	if err == nil || !strings.Contains(err.Error(), stateFile+backupSuffix) {
+		t.Fatalf("replaceStateFile() error = %v, want the backup to be named", err)
+	}
+	if backup := readStateFile(t, stateFile+backupSuffix); string(backup) != "previous" {
+		t.Errorf("backup = %q, want the previous content", backup)
+	}
+
+	if err := replaceStateFile(stateFile, io.NopCloser(nil), FileOptions{Backup: true}, writeString("last"), nil); err != nil {
+		t.Fatalf("replaceStateFile() error = %v", err)
+	}
+	if _, err := os.Stat(stateFile + backupSuffix); !os.IsNotExist(err) {
+		t.Errorf("backup still exists after a verified write: %v", err)
+	}
+	if content := readStateFile(t, stateFile); string(content) != "last" {
+		t.Errorf("state file = %q, want the new content", content)
+	}
+}
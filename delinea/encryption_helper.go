@@ -1,17 +1,16 @@
 package delinea
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
-
-	"golang.org/x/crypto/pbkdf2"
 )
 
 // Define constants for salt length and key length
@@ -19,118 +18,415 @@ const saltLength = 16
 const keyLength = 32
 const iterations = 100000
 
-func fileExists(filename string) bool {
-	_, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
+// An encrypted state file starts with a header that describes how it was encrypted:
+//
+//	magic       6 bytes  "TSSENC"
+//	version     1 byte   envelopeVersion
+//	kdf         1 byte ID, then its parameters; see KDFParams.marshal
+//	salt        1 byte length, then the salt
+//	nonce       1 byte length, then the nonce, or the nonce prefix in version 2
+//	chunk size  4 bytes, big-endian, in version 2 only
+//
+// In version 1, the AES-256-GCM ciphertext of the whole state follows. In version 2,
+// the state is encrypted in segments; see NewEncryptWriter. The whole header is
+// authenticated as additional data, so changing any of it makes decryption fail.
+var envelopeMagic = []byte("TSSENC")
+
+// Versions of the envelope format
+const (
+	envelopeVersionSingle = 1 // the state is sealed in one piece
+	envelopeVersionStream = 2 // the state is sealed in segments
+)
+
+// envelopeVersion is the version of the format written by EncryptFile
+const envelopeVersion = envelopeVersionStream
+
+// maxEnvelopeHeaderLength is the longest possible header, which is read before the
+// rest of a stream
+const maxEnvelopeHeaderLength = 6 + 1 + 10 + 256 + 256 + 4
+
+// ErrTruncated is returned when an encrypted state file ends before its header or
+// authentication tag is complete
+var ErrTruncated = errors.New("the encrypted state file is truncated")
+
+// ErrAlreadyEncrypted is returned by EncryptFile when it leaves a file that is already
+// encrypted as it is
+var ErrAlreadyEncrypted = errors.New("the file is already encrypted")
+
+// ErrNotEncrypted is returned for a plaintext state file where an encrypted one is
+// expected, and by DecryptFile when it leaves a plaintext file as it is
+var ErrNotEncrypted = errors.New("the file is not encrypted")
+
+// StateFormat is the format of a state file
+type StateFormat int
+
+const (
+	// FormatPlaintext is an unencrypted state file
+	FormatPlaintext StateFormat = iota
+	// FormatEncrypted is a state file encrypted in the envelope format
+	FormatEncrypted
+	// FormatLegacy is a state file encrypted by earlier versions as a base64 blob of
+	// salt, nonce and ciphertext, without a header
+	FormatLegacy
+)
+
+func (f StateFormat) String() string {
+	switch f {
+	case FormatEncrypted:
+		return "encrypted"
+	case FormatLegacy:
+		return "encrypted (legacy format)"
+	default:
+		return "plaintext"
 	}
-	return err == nil
 }
 
-// EncryptFile encrypts the file content
-func EncryptFile(passphrase, stateFile string) error {
-	if !fileExists(stateFile) {
-		return nil
+// envelopeHeader is the header of an encrypted state file
+type envelopeHeader struct {
+	Version   byte
+	KDF       KDFParams
+	Salt      []byte
+	Nonce     []byte
+	ChunkSize uint32
+}
+
+// marshal returns the header as written at the start of the file
+func (h envelopeHeader) marshal() []byte {
+	header := append([]byte{}, envelopeMagic...)
+	header = append(header, h.Version)
+	header = h.KDF.marshal(header)
+	header = append(header, byte(len(h.Salt)))
+	header = append(header, h.Salt...)
+	header = append(header, byte(len(h.Nonce)))
+	header = append(header, h.Nonce...)
+	if h.Version >= envelopeVersionStream {
+		header = binary.BigEndian.AppendUint32(header, h.ChunkSize)
 	}
+	return header
+}
 
-	// Read the input file
-	data, err := os.ReadFile(stateFile)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %v", err)
+// parseEnvelopeHeader reads the header at the start of data and returns it with its
+// length in bytes
+func parseEnvelopeHeader(data []byte) (envelopeHeader, int, error) {
+	var h envelopeHeader
+
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return h, 0, errors.New("the file is not an encrypted state file")
+	}
+	rest := data[len(envelopeMagic):]
+
+	if len(rest) < 1 {
+		return h, 0, fmt.Errorf("%w: the header ends after %d bytes", ErrTruncated, len(data))
+	}
+	h.Version, rest = rest[0], rest[1:]
+	if h.Version != envelopeVersionSingle && h.Version != envelopeVersionStream {
+		return h, 0, fmt.Errorf("unsupported encrypted state file version %d; a newer provider may be needed", h.Version)
+	}
+
+	var err error
+	if h.KDF, rest, err = parseKDFParams(rest); err != nil {
+		return h, 0, err
+	}
+
+	var ok bool
+	if h.Salt, rest, ok = readLengthPrefixed(rest); !ok {
+		return h, 0, fmt.Errorf("%w: the salt is incomplete", ErrTruncated)
+	}
+	if h.Nonce, rest, ok = readLengthPrefixed(rest); !ok {
+		return h, 0, fmt.Errorf("%w: the nonce is incomplete", ErrTruncated)
+	}
+
+	if h.Version >= envelopeVersionStream {
+		if len(rest) < 4 {
+			return h, 0, fmt.Errorf("%w: the chunk size is incomplete", ErrTruncated)
+		}
+		h.ChunkSize, rest = binary.BigEndian.Uint32(rest), rest[4:]
+		if h.ChunkSize == 0 || h.ChunkSize > maxChunkSize {
+			return h, 0, fmt.Errorf("invalid chunk size %d", h.ChunkSize)
+		}
+	}
+
+	return h, len(data) - len(rest), nil
+}
+
+// readLengthPrefixed reads a field preceded by its length in one byte
+func readLengthPrefixed(data []byte) ([]byte, []byte, bool) {
+	if len(data) < 1 || len(data) < 1+int(data[0]) {
+		return nil, nil, false
+	}
+	n := int(data[0])
+	return data[1 : 1+n], data[1+n:], true
+}
+
+// DetectFormat returns the format of the content of a state file
+func DetectFormat(data []byte) StateFormat {
+	if bytes.HasPrefix(data, envelopeMagic) {
+		return FormatEncrypted
 	}
 
-	// Generate a random salt
-	salt := make([]byte, saltLength)
-	if _, err := rand.Read(salt); err != nil {
-		return fmt.Errorf("failed to generate salt: %v", err)
+	// Legacy files are a single base64 blob, while JSON state starts with '{'. A
+	// truncated blob is still detected, so that decrypting it reports the truncation.
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return FormatPlaintext
+	}
+	for _, c := range trimmed {
+		if !isBase64Char(c) {
+			return FormatPlaintext
+		}
 	}
+	return FormatLegacy
+}
 
-	// Derive the encryption key using PBKDF2
-	key := pbkdf2.Key([]byte(passphrase), salt, iterations, keyLength, sha256.New)
+// isBase64Char reports whether c belongs to the standard base64 alphabet or padding
+func isBase64Char(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '+' || c == '/' || c == '='
+}
 
-	// Encrypt the data
+// newGCM returns AES-256-GCM with the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return fmt.Errorf("failed to create cipher block: %v", err)
+		return nil, fmt.Errorf("failed to create cipher block: %v", err)
 	}
 
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return fmt.Errorf("failed to create GCM: %v", err)
+		return nil, fmt.Errorf("failed to create GCM: %v", err)
+	}
+	return gcm, nil
+}
+
+// EncryptState encrypts plaintext state into the envelope format, deriving the key
+// with the given function
+func EncryptState(passphrase string, plaintext []byte, kdf KDFParams) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(plaintext) + len(plaintext)/chunkSize*16 + 128)
+
+	w, err := NewEncryptWriter(&buf, passphrase, kdf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StateKDF returns the key derivation function recorded in an encrypted state file.
+// Files in the legacy format always used PBKDF2.
+func StateKDF(data []byte) (KDFParams, error) {
+	switch DetectFormat(data) {
+	case FormatPlaintext:
+		return KDFParams{}, ErrNotEncrypted
+	case FormatLegacy:
+		return defaultKDFParams[KDFPBKDF2], nil
+	}
+
+	header, _, err := parseEnvelopeHeader(data)
+	return header.KDF, err
+}
+
+// DecryptState decrypts a state file in the envelope or the legacy format. Use
+// NewDecryptReader for large files.
+func DecryptState(passphrase string, data []byte) ([]byte, error) {
+	switch DetectFormat(data) {
+	case FormatPlaintext:
+		return nil, ErrNotEncrypted
+	case FormatLegacy:
+		return decryptLegacyState(passphrase, data)
+	}
+
+	header, n, err := parseEnvelopeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	if header.Version == envelopeVersionStream {
+		r, err := newDecryptReader(bytes.NewReader(data[n:]), passphrase, header, data[:n])
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
 	}
 
-	nonce := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return fmt.Errorf("failed to generate nonce: %v", err)
+	key, err := header.KDF.deriveKey(passphrase, header.Salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(header.Nonce))
 	}
 
-	// Encrypt the data using GCM
-	encryptedData := gcm.Seal(nonce, nonce, data, nil)
+	ciphertext := data[n:]
+	if len(ciphertext) < gcm.Overhead() {
+		return nil, fmt.Errorf("%w: the ciphertext is incomplete", ErrTruncated)
+	}
 
-	// Prepend the salt to the encrypted data
-	finalData := append(salt, encryptedData...)
+	plaintext, err := gcm.Open(nil, header.Nonce, ciphertext, data[:n])
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plaintext, nil
+}
 
-	// Write the encrypted data to the state file
-	err = os.WriteFile(stateFile, []byte(base64.StdEncoding.EncodeToString(finalData)), 0644)
+// decryptLegacyState decrypts a base64 blob of salt, nonce and ciphertext written by
+// earlier versions, which always used PBKDF2 with the default iterations
+func decryptLegacyState(passphrase string, data []byte) ([]byte, error) {
+	// Decode the base64-encoded encrypted data
+	encryptedData, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
 	if err != nil {
-		return fmt.Errorf("failed to write encrypted data to state file: %v", err)
+		return nil, fmt.Errorf("failed to decode base64 data, the file may be truncated: %v", err)
 	}
 
-	log.Printf("[DEBUG] File encrypted successfully: %s\n", stateFile)
-	return nil
+	if len(encryptedData) < saltLength {
+		return nil, fmt.Errorf("%w: the salt is incomplete", ErrTruncated)
+	}
+	salt, encryptedContent := encryptedData[:saltLength], encryptedData[saltLength:]
+
+	key, err := defaultKDFParams[KDFPBKDF2].deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(encryptedContent) < nonceSize+gcm.Overhead() {
+		return nil, fmt.Errorf("%w: the ciphertext is incomplete", ErrTruncated)
+	}
+	nonce, ciphertext := encryptedContent[:nonceSize], encryptedContent[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return plaintext, nil
 }
 
-// DecryptFile decrypts the content of the state file
-func DecryptFile(passphrase, stateFile string) error {
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false
+	}
+	return err == nil
+}
+
+// EncryptFile encrypts the file content with the default key derivation function
+func EncryptFile(passphrase, stateFile string) error {
+	return EncryptFileWithOptions(passphrase, stateFile, FileOptions{})
+}
+
+// EncryptFileWithOptions encrypts the file content, deriving the key with the function
+// of the options. A file that is already encrypted is left as it is, so that it is not
+// encrypted twice, and ErrAlreadyEncrypted is returned. The state is encrypted as it
+// is read, so that large files are not held in memory.
+func EncryptFileWithOptions(passphrase, stateFile string, opts FileOptions) error {
 	if !fileExists(stateFile) {
 		return nil
 	}
 
-	// Read the encrypted file
-	encryptedBase64Data, err := os.ReadFile(stateFile)
+	// Read the input file
+	f, r, format, err := openStateFile(stateFile)
 	if err != nil {
-		return fmt.Errorf("failed to read encrypted file: %v", err)
+		return fmt.Errorf("failed to read input file: %v", err)
 	}
+	defer f.Close()
 
-	// Decode the base64-encoded encrypted data
-	encryptedData, err := base64.StdEncoding.DecodeString(string(encryptedBase64Data))
-	if err != nil {
-		return fmt.Errorf("failed to decode base64 data: %v", err)
+	if format != FormatPlaintext {
+		return ErrAlreadyEncrypted
 	}
 
-	// Extract the salt and encrypted data
-	salt := encryptedData[:saltLength]
-	encryptedContent := encryptedData[saltLength:]
+	// Replace the state file with the encrypted data
+	plaintext := sha256.New()
+	err = replaceStateFile(stateFile, f, opts, func(w io.Writer) error {
+		ew, err := NewEncryptWriter(w, passphrase, opts.kdf())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ew, io.TeeReader(r, plaintext)); err != nil {
+			return fmt.Errorf("failed to read input file: %v", err)
+		}
+		return ew.Close()
+	}, decryptsTo(passphrase, plaintext))
+	return err
+}
 
-	// Derive the decryption key using PBKDF2
-	key := pbkdf2.Key([]byte(passphrase), salt, iterations, keyLength, sha256.New)
+// DecryptFile decrypts the content of the state file. A plaintext file is left as it
+// is, so that the state of a new workspace can be decrypted before its first apply,
+// and ErrNotEncrypted is returned.
+func DecryptFile(passphrase, stateFile string) error {
+	return DecryptFileWithOptions(passphrase, stateFile, FileOptions{})
+}
 
-	// Decrypt the data
-	block, err := aes.NewCipher(key)
+// DecryptFileWithOptions decrypts the content of the state file like DecryptFile,
+// keeping a backup of the encrypted content if the options ask for it
+func DecryptFileWithOptions(passphrase, stateFile string, opts FileOptions) error {
+	if !fileExists(stateFile) {
+		return nil
+	}
+
+	// Read the encrypted file
+	f, r, format, err := openStateFile(stateFile)
 	if err != nil {
-		return fmt.Errorf("failed to create cipher block: %v", err)
+		return fmt.Errorf("failed to read encrypted file: %v", err)
 	}
+	defer f.Close()
 
-	gcm, err := cipher.NewGCM(block)
+	if format == FormatPlaintext {
+		return ErrNotEncrypted
+	}
+
+	decrypted, err := NewDecryptReader(r, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to create GCM: %v", err)
+		return err
 	}
 
-	nonceSize := gcm.NonceSize()
-	nonce, ciphertext := encryptedContent[:nonceSize], encryptedContent[nonceSize:]
+	// Replace the state file with the decrypted data
+	err = replaceStateFile(stateFile, f, opts, func(w io.Writer) error {
+		_, err := io.Copy(w, decrypted)
+		return err
+	}, nil)
+	return err
+}
 
-	// Decrypt the data using GCM
-	decryptedData, err := gcm.Open(nil, nonce, ciphertext, nil)
+// RekeyFile encrypts an encrypted state file again with a new passphrase, deriving the
+// new key with the function of the options. Files in the legacy format are rewritten
+// in the envelope format.
+func RekeyFile(oldPassphrase, newPassphrase, stateFile string, opts FileOptions) error {
+	f, r, format, err := openStateFile(stateFile)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt data: %v", err)
+		return fmt.Errorf("failed to read encrypted file: %v", err)
+	}
+	defer f.Close()
+
+	if format == FormatPlaintext {
+		return fmt.Errorf("%s is not encrypted", stateFile)
 	}
 
-	// Write the decrypted data to the state file
-	err = os.WriteFile(stateFile, decryptedData, 0644)
+	decrypted, err := NewDecryptReader(r, oldPassphrase)
 	if err != nil {
-		return fmt.Errorf("failed to write decrypted data to state file: %v", err)
+		return err
 	}
 
-	log.Printf("[DEBUG] File decrypted successfully: %s\n", stateFile)
-	return nil
+	plaintext := sha256.New()
+	err = replaceStateFile(stateFile, f, opts, func(w io.Writer) error {
+		ew, err := NewEncryptWriter(w, newPassphrase, opts.kdf())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ew, io.TeeReader(decrypted, plaintext)); err != nil {
+			return err
+		}
+		return ew.Close()
+	}, decryptsTo(newPassphrase, plaintext))
+	return err
 }
@@ -0,0 +1,334 @@
+package delinea
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testState = `{"version": 4, "resources": [{"type": "tss_secret", "value": "s3cret!"}]}`
+
+// testKDF keeps the key derivation cheap in tests that are not about it
+var testKDF = KDFParams{Name: KDFArgon2id, Iterations: 1, MemoryKiB: 64, Parallelism: 1}
+
+// writeStateFile writes a state file into a temporary directory
+func writeStateFile(t *testing.T, content []byte) string {
+	t.Helper()
+
+	stateFile := filepath.Join(t.TempDir(), "terraform.tfstate")
+	if err := os.WriteFile(stateFile, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return stateFile
+}
+
+// readStateFile returns the content of a state file
+func readStateFile(t *testing.T, stateFile string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestEncryptDecryptFile(t *testing.T) {
+	stateFile := writeStateFile(t, []byte(testState))
+
+	if err := EncryptFileWithOptions("passphrase", stateFile, FileOptions{KDF: testKDF}); err != nil {
+		t.Fatalf("EncryptFile() error = %v", err)
+	}
+	encrypted := readStateFile(t, stateFile)
+	if DetectFormat(encrypted) != FormatEncrypted || bytes.Contains(encrypted, []byte("s3cret!")) {
+		t.Fatalf("encrypted file = %q, want an envelope without the state", encrypted)
+	}
+
+	// Encrypting again leaves the file as it is
+	if err := EncryptFileWithOptions("passphrase", stateFile, FileOptions{KDF: testKDF}); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Fatalf("EncryptFile() of an encrypted file error = %v, want %v", err, ErrAlreadyEncrypted)
+	}
+	if !bytes.Equal(readStateFile(t, stateFile), encrypted) {
+		t.Fatal("EncryptFile() encrypted the file twice")
+	}
+
+	if err := DecryptFile("wrong", stateFile); err == nil {
+		t.Error("DecryptFile() with the wrong passphrase succeeded")
+	}
+	if err := DecryptFile("passphrase", stateFile); err != nil {
+		t.Fatalf("DecryptFile() error = %v", err)
+	}
+	if got := string(readStateFile(t, stateFile)); got != testState {
+		t.Fatalf("decrypted file = %q, want the original state", got)
+	}
+
+	// Decrypting a plaintext file leaves it as it is
+	if err := DecryptFile("passphrase", stateFile); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("DecryptFile() of a plaintext file error = %v, want %v", err, ErrNotEncrypted)
+	}
+	if got := string(readStateFile(t, stateFile)); got != testState {
+		t.Fatalf("plaintext file = %q after DecryptFile(), want it unchanged", got)
+	}
+}
+
+func TestDecryptStateTruncated(t *testing.T) {
+	encrypted, err := EncryptState("passphrase", []byte(testState), testKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, headerLength, err := parseEnvelopeHeader(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n := len(envelopeMagic); n < len(encrypted); n++ {
+		_, err := DecryptState("passphrase", encrypted[:n])
+		if err == nil {
+			t.Fatalf("DecryptState() of %d of %d bytes succeeded", n, len(encrypted))
+		}
+		// Cutting the header or the tag is reported as truncation; cutting the
+		// ciphertext fails authentication
+		if n < headerLength+16 && !errors.Is(err, ErrTruncated) {
+			t.Errorf("DecryptState() of %d bytes error = %v, want ErrTruncated", n, err)
+		}
+	}
+}
+
+func TestDecryptStateTampered(t *testing.T) {
+	encrypted, err := EncryptState("passphrase", []byte(testState), testKDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]int{
+		"version":    len(envelopeMagic),
+		"kdf":        len(envelopeMagic) + 1,
+		"passes":     len(envelopeMagic) + 5,
+		"salt":       len(envelopeMagic) + 12,
+		"ciphertext": len(encrypted) - 20,
+	}
+	for name, offset := range tests {
+		t.Run(name, func(t *testing.T) {
+			tampered := bytes.Clone(encrypted)
+			tampered[offset] ^= 1
+			if _, err := DecryptState("passphrase", tampered); err == nil {
+				t.Error("DecryptState() of a tampered file succeeded")
+			}
+		})
+	}
+}
+
+func TestDecryptLegacyFile(t *testing.T) {
+	// Earlier versions wrote base64(salt || nonce || ciphertext) without a header
+	salt := make([]byte, saltLength)
+	rand.Read(salt)
+	key, err := defaultKDFParams[KDFPBKDF2].deriveKey("passphrase", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	legacy := append(salt, gcm.Seal(nonce, nonce, []byte(testState), nil)...)
+	encoded := base64.StdEncoding.EncodeToString(legacy)
+
+	if got := DetectFormat([]byte(encoded)); got != FormatLegacy {
+		t.Fatalf("DetectFormat() = %s, want legacy", got)
+	}
+	if _, err := DecryptState("passphrase", []byte(encoded[:40])); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("DecryptState() of a truncated legacy file error = %v, want a truncation error", err)
+	}
+
+	stateFile := writeStateFile(t, []byte(encoded))
+	if err := EncryptFileWithOptions("passphrase", stateFile, FileOptions{KDF: testKDF}); !errors.Is(err, ErrAlreadyEncrypted) {
+		t.Fatalf("EncryptFile() of a legacy file error = %v, want %v", err, ErrAlreadyEncrypted)
+	}
+	if err := DecryptFile("passphrase", stateFile); err != nil {
+		t.Fatalf("DecryptFile() error = %v", err)
+	}
+	if got := string(readStateFile(t, stateFile)); got != testState {
+		t.Fatalf("decrypted legacy file = %q, want the original state", got)
+	}
+}
+
+func TestRekeyFile(t *testing.T) {
+	stateFile := writeStateFile(t, []byte(testState))
+
+	if err := RekeyFile("old", "new", stateFile, FileOptions{KDF: testKDF}); err == nil {
+		t.Error("RekeyFile() of a plaintext file succeeded")
+	}
+	if err := EncryptFileWithOptions("old", stateFile, FileOptions{KDF: testKDF}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RekeyFile("wrong", "new", stateFile, FileOptions{KDF: testKDF}); err == nil {
+		t.Error("RekeyFile() with the wrong passphrase succeeded")
+	}
+	if err := RekeyFile("old", "new", stateFile, FileOptions{KDF: testKDF}); err != nil {
+		t.Fatalf("RekeyFile() error = %v", err)
+	}
+	if err := DecryptFile("old", stateFile); err == nil {
+		t.Error("DecryptFile() with the old passphrase succeeded after rekeying")
+	}
+	if err := DecryptFile("new", stateFile); err != nil {
+		t.Fatalf("DecryptFile() with the new passphrase error = %v", err)
+	}
+	if got := string(readStateFile(t, stateFile)); got != testState {
+		t.Fatalf("decrypted file = %q, want the original state", got)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		data string
+		want StateFormat
+	}{
+		{data: "", want: FormatPlaintext},
+		{data: testState, want: FormatPlaintext},
+		{data: "TSSENC\x01", want: FormatEncrypted},
+		{data: "c2FsdA==\n", want: FormatLegacy},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat([]byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestEncryptStateKDFs(t *testing.T) {
+	for _, spec := range []string{"pbkdf2:i=1000", "argon2id:t=1,m=64,p=2", "scrypt:n=10,r=8,p=1"} {
+		t.Run(spec, func(t *testing.T) {
+			kdf, err := ParseKDF(spec)
+			if err != nil {
+				t.Fatalf("ParseKDF() error = %v", err)
+			}
+			encrypted, err := EncryptState("passphrase", []byte(testState), kdf)
+			if err != nil {
+				t.Fatalf("EncryptState() error = %v", err)
+			}
+
+			// The parameters are read back from the header
+			header, _, err := parseEnvelopeHeader(encrypted)
+			if err != nil || header.KDF != kdf {
+				t.Errorf("header KDF = %s, %v, want %s", header.KDF, err, kdf)
+			}
+
+			decrypted, err := DecryptState("passphrase", encrypted)
+			if err != nil || string(decrypted) != testState {
+				t.Errorf("DecryptState() = %q, %v, want the original state", decrypted, err)
+			}
+			if _, err := DecryptState("wrong", encrypted); err == nil {
+				t.Error("DecryptState() with the wrong passphrase succeeded")
+			}
+		})
+	}
+}
+
+func TestDecryptPBKDF2File(t *testing.T) {
+	// Written by the first version of the envelope format, which always used PBKDF2
+	// with 100000 iterations
+	encoded := "VFNTRU5DAQEAAYagEIjjRqXh463zdo+hcidUctgMOHZucsLrEY1tmn/MHv7okvDVqN6Wkh77xEJBFF2KHR4aS4qWQlxr6tj3"
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptState("passphrase", encrypted)
+	if err != nil || string(decrypted) != `{"version": 4}` {
+		t.Errorf("DecryptState() = %q, %v, want the original state", decrypted, err)
+	}
+
+	// Files in the first version are decrypted in one piece by the streaming reader
+	r, err := NewDecryptReader(bytes.NewReader(encrypted), "passphrase")
+	if err != nil {
+		t.Fatalf("NewDecryptReader() error = %v", err)
+	}
+	if decrypted, err := io.ReadAll(r); err != nil || string(decrypted) != `{"version": 4}` {
+		t.Errorf("NewDecryptReader() read %q, %v, want the original state", decrypted, err)
+	}
+}
+
+func TestEncryptFileDefaultKDF(t *testing.T) {
+	stateFile := writeStateFile(t, []byte(testState))
+
+	if err := EncryptFile("passphrase", stateFile); err != nil {
+		t.Fatalf("EncryptFile() error = %v", err)
+	}
+	header, _, err := parseEnvelopeHeader(readStateFile(t, stateFile))
+	if err != nil || header.KDF != DefaultKDF {
+		t.Errorf("header KDF = %s, %v, want %s", header.KDF, err, DefaultKDF)
+	}
+}
+
+func TestParseKDF(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    KDFParams
+		wantErr string
+	}{
+		{spec: "argon2id", want: DefaultKDF},
+		{spec: "Argon2id:m=131072,t=4", want: KDFParams{Name: KDFArgon2id, Iterations: 4, MemoryKiB: 131072, Parallelism: 4}},
+		{spec: "scrypt", want: KDFParams{Name: KDFScrypt, LogN: 15, BlockSize: 8, Parallelism: 1}},
+		{spec: "scrypt:n=17", want: KDFParams{Name: KDFScrypt, LogN: 17, BlockSize: 8, Parallelism: 1}},
+		{spec: "pbkdf2:i=600000", want: KDFParams{Name: KDFPBKDF2, Iterations: 600000}},
+		{spec: "bcrypt", wantErr: "unknown key derivation function"},
+		{spec: "scrypt:t=3", wantErr: "unknown scrypt parameter"},
+		{spec: "argon2id:m=lots", wantErr: "must be a positive number"},
+		{spec: "argon2id:t=0", wantErr: "invalid Argon2id passes"},
+		{spec: "argon2id:m=8,p=4", wantErr: "invalid Argon2id memory"},
+		{spec: "scrypt:n=30", wantErr: "invalid scrypt cost"},
+		{spec: "scrypt:n=24,r=1024", wantErr: "need too much memory"},
+		{spec: "hkdf", wantErr: "only used with a key from Secret Server"},
+	}
+	for _, tt := range tests {
+		got, err := ParseKDF(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseKDF(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseKDF(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+		if again, err := ParseKDF(got.String()); err != nil || again != got {
+			t.Errorf("ParseKDF(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+		}
+	}
+}
+
+func TestEncryptStateKey(t *testing.T) {
+	key, err := ParseStateKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, stateKeyLength)) + "\n")
+	if err != nil {
+		t.Fatalf("ParseStateKey() error = %v", err)
+	}
+	for _, value := range []string{"c2hvcnQ=", "not base64!"} {
+		if _, err := ParseStateKey(value); err == nil {
+			t.Errorf("ParseStateKey(%q) succeeded", value)
+		}
+	}
+
+	encrypted, err := EncryptState(string(key), []byte(testState), KeyKDF)
+	if err != nil {
+		t.Fatalf("EncryptState() error = %v", err)
+	}
+	if kdf, err := StateKDF(encrypted); err != nil || kdf != KeyKDF {
+		t.Errorf("StateKDF() = %s, %v, want %s", kdf, err, KeyKDF)
+	}
+
+	decrypted, err := DecryptState(string(key), encrypted)
+	if err != nil || string(decrypted) != testState {
+		t.Errorf("DecryptState() = %q, %v, want the original state", decrypted, err)
+	}
+	if _, err := DecryptState("passphrase", encrypted); err == nil || !strings.Contains(err.Error(), "not with a passphrase") {
+		t.Errorf("DecryptState() with a passphrase error = %v, want a hint at the key", err)
+	}
+}
@@ -0,0 +1,290 @@
+package delinea
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Key derivation functions of the envelope, recorded in the header by ID
+const (
+	kdfPBKDF2SHA256 = 1
+	kdfArgon2id     = 2
+	kdfScrypt       = 3
+	kdfHKDF         = 4
+)
+
+// Names of the key derivation functions. ParseKDF accepts all but KDFHKDF, which
+// expands a random key instead of a passphrase; see KeyKDF.
+const (
+	KDFPBKDF2   = "pbkdf2"
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+	KDFHKDF     = "hkdf"
+)
+
+// stateKeyLength is the minimum length of a key used with KeyKDF
+const stateKeyLength = 32
+
+// hkdfInfo binds the keys expanded with HKDF to state encryption
+const hkdfInfo = "terraform-provider-tss state encryption"
+
+// Limits on the cost parameters read from a file, so that a corrupted header cannot
+// make decryption run for hours or exhaust the memory
+const (
+	maxIterations      = 10_000_000 // PBKDF2 iterations
+	maxArgon2Passes    = 100
+	maxArgon2MemoryKiB = 4 << 20 // 4 GiB
+	maxScryptLogN      = 24
+	maxScryptMemory    = 4 << 30 // 4 GiB, with scrypt using 128 * r * N bytes
+)
+
+// KDFParams selects the key derivation function that turns the passphrase into the
+// encryption key, with its cost parameters. The parameters are recorded in the header
+// of the encrypted file, so that changing them does not affect existing files.
+type KDFParams struct {
+	Name        string // KDFPBKDF2, KDFArgon2id or KDFScrypt
+	Iterations  uint32 // PBKDF2 iterations, or Argon2id passes over the memory
+	MemoryKiB   uint32 // Argon2id memory in KiB
+	Parallelism uint8  // Argon2id threads, or scrypt p
+	LogN        uint8  // scrypt CPU and memory cost, as the base 2 logarithm of N
+	BlockSize   uint32 // scrypt r
+}
+
+// DefaultKDF is used to encrypt files when no key derivation function is given. Its
+// parameters follow the second recommended option of RFC 9106.
+var DefaultKDF = KDFParams{Name: KDFArgon2id, Iterations: 3, MemoryKiB: 64 * 1024, Parallelism: 4}
+
+// KeyKDF encrypts files with a random data encryption key, such as one stored in a
+// Secret Server secret, instead of a passphrase. The key is used in place of the
+// passphrase and expanded with HKDF-SHA256 and the salt of each file, since a random key
+// does not need a slow key derivation function.
+var KeyKDF = KDFParams{Name: KDFHKDF}
+
+// defaultKDFParams are the cost parameters of each key derivation function when they
+// are not given
+var defaultKDFParams = map[string]KDFParams{
+	KDFPBKDF2:   {Name: KDFPBKDF2, Iterations: iterations},
+	KDFArgon2id: DefaultKDF,
+	KDFScrypt:   {Name: KDFScrypt, LogN: 15, BlockSize: 8, Parallelism: 1},
+}
+
+// ParseKDF parses a key derivation function with optional cost parameters, such as
+// "argon2id", "argon2id:t=4,m=131072,p=2", "scrypt:n=16,r=8,p=1" or
+// "pbkdf2:i=600000". Argon2id takes the passes t, the memory m in KiB and the
+// threads p; scrypt takes log2(N) n, r and p; PBKDF2 takes the iterations i.
+func ParseKDF(spec string) (KDFParams, error) {
+	name, options, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	params, ok := defaultKDFParams[strings.ToLower(name)]
+	if strings.EqualFold(name, KDFHKDF) {
+		return KDFParams{}, fmt.Errorf("%s is only used with a key from Secret Server", KDFHKDF)
+	}
+	if !ok {
+		return KDFParams{}, fmt.Errorf("unknown key derivation function '%s'; use %s, %s or %s", name, KDFArgon2id, KDFScrypt, KDFPBKDF2)
+	}
+
+	if options != "" {
+		for _, option := range strings.Split(options, ",") {
+			key, value, _ := strings.Cut(option, "=")
+			n, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return KDFParams{}, fmt.Errorf("invalid %s parameter '%s': the value must be a positive number", params.Name, option)
+			}
+
+			switch params.Name + ":" + strings.TrimSpace(key) {
+			case KDFPBKDF2 + ":i", KDFArgon2id + ":t":
+				params.Iterations = uint32(n)
+			case KDFArgon2id + ":m":
+				params.MemoryKiB = uint32(n)
+			case KDFArgon2id + ":p", KDFScrypt + ":p":
+				if n > 255 {
+					return KDFParams{}, fmt.Errorf("invalid %s parameter '%s': p must be at most 255", params.Name, option)
+				}
+				params.Parallelism = uint8(n)
+			case KDFScrypt + ":n":
+				if n > 255 {
+					return KDFParams{}, fmt.Errorf("invalid %s parameter '%s': n is the base 2 logarithm of N", params.Name, option)
+				}
+				params.LogN = uint8(n)
+			case KDFScrypt + ":r":
+				params.BlockSize = uint32(n)
+			default:
+				return KDFParams{}, fmt.Errorf("unknown %s parameter '%s'", params.Name, key)
+			}
+		}
+	}
+
+	return params, params.validate()
+}
+
+// String returns the parameters in the form accepted by ParseKDF
+func (p KDFParams) String() string {
+	switch p.Name {
+	case KDFPBKDF2:
+		return fmt.Sprintf("%s:i=%d", p.Name, p.Iterations)
+	case KDFArgon2id:
+		return fmt.Sprintf("%s:t=%d,m=%d,p=%d", p.Name, p.Iterations, p.MemoryKiB, p.Parallelism)
+	case KDFScrypt:
+		return fmt.Sprintf("%s:n=%d,r=%d,p=%d", p.Name, p.LogN, p.BlockSize, p.Parallelism)
+	}
+	return p.Name
+}
+
+// validate checks the cost parameters against the limits of the function and those
+// accepted when decrypting
+func (p KDFParams) validate() error {
+	switch p.Name {
+	case KDFPBKDF2:
+		if p.Iterations == 0 || p.Iterations > maxIterations {
+			return fmt.Errorf("invalid PBKDF2 iteration count %d", p.Iterations)
+		}
+	case KDFArgon2id:
+		if p.Iterations == 0 || p.Iterations > maxArgon2Passes {
+			return fmt.Errorf("invalid Argon2id passes %d, want 1 to %d", p.Iterations, maxArgon2Passes)
+		}
+		if p.Parallelism == 0 {
+			return fmt.Errorf("invalid Argon2id threads %d", p.Parallelism)
+		}
+		if p.MemoryKiB < 8*uint32(p.Parallelism) || p.MemoryKiB > maxArgon2MemoryKiB {
+			return fmt.Errorf("invalid Argon2id memory %d KiB, want %d to %d", p.MemoryKiB, 8*uint32(p.Parallelism), maxArgon2MemoryKiB)
+		}
+	case KDFScrypt:
+		if p.LogN < 1 || p.LogN > maxScryptLogN {
+			return fmt.Errorf("invalid scrypt cost n=%d, want 1 to %d", p.LogN, maxScryptLogN)
+		}
+		if p.BlockSize == 0 || p.Parallelism == 0 {
+			return fmt.Errorf("invalid scrypt parameters r=%d, p=%d", p.BlockSize, p.Parallelism)
+		}
+		if uint64(128)*uint64(p.BlockSize)<<p.LogN > maxScryptMemory || uint64(p.BlockSize)*uint64(p.Parallelism) >= 1<<30 {
+			return fmt.Errorf("scrypt parameters n=%d, r=%d, p=%d need too much memory", p.LogN, p.BlockSize, p.Parallelism)
+		}
+	case KDFHKDF:
+		if p != KeyKDF {
+			return errors.New("HKDF does not take parameters")
+		}
+	default:
+		return fmt.Errorf("unknown key derivation function '%s'", p.Name)
+	}
+	return nil
+}
+
+// deriveKey derives the encryption key from the passphrase
+func (p KDFParams) deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	switch p.Name {
+	case KDFPBKDF2:
+		return pbkdf2.Key([]byte(passphrase), salt, int(p.Iterations), keyLength, sha256.New), nil
+	case KDFArgon2id:
+		return argon2.IDKey([]byte(passphrase), salt, p.Iterations, p.MemoryKiB, p.Parallelism, keyLength), nil
+	case KDFScrypt:
+		key, err := scrypt.Key([]byte(passphrase), salt, 1<<p.LogN, int(p.BlockSize), int(p.Parallelism), keyLength)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %v", err)
+		}
+		return key, nil
+	case KDFHKDF:
+		if len(passphrase) < stateKeyLength {
+			return nil, fmt.Errorf("the state is encrypted with a key of at least %d bytes from Secret Server, not with a passphrase", stateKeyLength)
+		}
+		return hkdf.Key(sha256.New, []byte(passphrase), salt, hkdfInfo, keyLength)
+	}
+	return nil, fmt.Errorf("unknown key derivation function '%s'", p.Name)
+}
+
+// marshal appends the function ID and its parameters as written in the header:
+//
+//	pbkdf2    1, iterations (4 bytes)
+//	argon2id  2, passes (4 bytes), memory in KiB (4 bytes), threads (1 byte)
+//	scrypt    3, log2(N) (1 byte), r (4 bytes), p (1 byte)
+//	hkdf      4
+//
+// Integers are big-endian.
+func (p KDFParams) marshal(header []byte) []byte {
+	switch p.Name {
+	case KDFPBKDF2:
+		header = append(header, kdfPBKDF2SHA256)
+		header = binary.BigEndian.AppendUint32(header, p.Iterations)
+	case KDFArgon2id:
+		header = append(header, kdfArgon2id)
+		header = binary.BigEndian.AppendUint32(header, p.Iterations)
+		header = binary.BigEndian.AppendUint32(header, p.MemoryKiB)
+		header = append(header, p.Parallelism)
+	case KDFScrypt:
+		header = append(header, kdfScrypt, p.LogN)
+		header = binary.BigEndian.AppendUint32(header, p.BlockSize)
+		header = append(header, p.Parallelism)
+	case KDFHKDF:
+		header = append(header, kdfHKDF)
+	}
+	return header
+}
+
+// parseKDFParams reads the function ID and its parameters at the start of data and
+// returns them with the rest of data
+func parseKDFParams(data []byte) (KDFParams, []byte, error) {
+	var p KDFParams
+
+	if len(data) < 1 {
+		return p, nil, fmt.Errorf("%w: the key derivation function is missing", ErrTruncated)
+	}
+	id, rest := data[0], data[1:]
+
+	var size int
+	switch id {
+	case kdfPBKDF2SHA256:
+		size = 4
+	case kdfArgon2id:
+		size = 9
+	case kdfScrypt:
+		size = 6
+	case kdfHKDF:
+		size = 0
+	default:
+		return p, nil, fmt.Errorf("unsupported key derivation function %d; a newer provider may be needed", id)
+	}
+	if len(rest) < size {
+		return p, nil, fmt.Errorf("%w: the key derivation parameters are incomplete", ErrTruncated)
+	}
+
+	switch id {
+	case kdfPBKDF2SHA256:
+		p = KDFParams{Name: KDFPBKDF2, Iterations: binary.BigEndian.Uint32(rest)}
+	case kdfArgon2id:
+		p = KDFParams{
+			Name:        KDFArgon2id,
+			Iterations:  binary.BigEndian.Uint32(rest),
+			MemoryKiB:   binary.BigEndian.Uint32(rest[4:]),
+			Parallelism: rest[8],
+		}
+	case kdfScrypt:
+		p = KDFParams{Name: KDFScrypt, LogN: rest[0], BlockSize: binary.BigEndian.Uint32(rest[1:]), Parallelism: rest[5]}
+	case kdfHKDF:
+		p = KeyKDF
+	}
+
+	if err := p.validate(); err != nil {
+		return p, nil, err
+	}
+	return p, rest[size:], nil
+}
+
+// ParseStateKey decodes a data encryption key for KeyKDF, stored in base64 as created
+// by "openssl rand -base64 32"
+func ParseStateKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, errors.New("the key is not base64 encoded")
+	}
+	if len(key) < stateKeyLength {
+		return nil, fmt.Errorf("the key has %d bytes, want at least %d random bytes", len(key), stateKeyLength)
+	}
+	return key, nil
+}
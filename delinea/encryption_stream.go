@@ -0,0 +1,305 @@
+package delinea
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// chunkSize is the size of the plaintext segments written by NewEncryptWriter
+const chunkSize = 64 * 1024
+
+// maxChunkSize limits the segment size read from a header, so that a corrupted header
+// cannot make decryption allocate a large buffer
+const maxChunkSize = 16 * 1024 * 1024
+
+// The nonce of each segment is the nonce prefix from the header, the segment counter
+// and a flag that marks the last segment
+const (
+	noncePrefixLength = 7
+	lastSegmentFlag   = 1
+)
+
+// errDecrypt is returned when a segment fails authentication
+var errDecrypt = errors.New("failed to decrypt data: the passphrase is wrong or the file is corrupted or truncated")
+
+// encryptWriter seals the plaintext written to it in segments
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte // plaintext of the current segment, with room for the tag
+	n       int    // length of the plaintext in buf
+	err     error
+}
+
+// NewEncryptWriter writes the envelope header to w and returns a writer that encrypts
+// the state written to it, deriving the key with the given function. The state must
+// be finished with Close, which does not close w.
+//
+// The state is split into segments of 64 KiB that are sealed with AES-256-GCM as in
+// the STREAM construction: the nonce of each segment holds its position and whether it
+// is the last one, so that segments cannot be reordered, dropped or appended, and a
+// file cannot be cut at a segment boundary. The last segment is always shorter than
+// the others, and empty if the state fills the previous segment.
+func NewEncryptWriter(w io.Writer, passphrase string, kdf KDFParams) (io.WriteCloser, error) {
+	return newEncryptWriter(w, passphrase, kdf, chunkSize)
+}
+
+func newEncryptWriter(w io.Writer, passphrase string, kdf KDFParams, size int) (*encryptWriter, error) {
+	if err := kdf.validate(); err != nil {
+		return nil, err
+	}
+
+	header := envelopeHeader{
+		Version:   envelopeVersionStream,
+		KDF:       kdf,
+		Salt:      make([]byte, saltLength),
+		Nonce:     make([]byte, noncePrefixLength),
+		ChunkSize: uint32(size),
+	}
+
+	// Generate a random salt and nonce prefix
+	if _, err := rand.Read(header.Salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	if _, err := rand.Read(header.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	key, err := kdf.deriveKey(passphrase, header.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := header.marshal()
+	if _, err := w.Write(aad); err != nil {
+		return nil, fmt.Errorf("failed to write header: %v", err)
+	}
+
+	return &encryptWriter{
+		w:      w,
+		aead:   aead,
+		aad:    aad,
+		prefix: header.Nonce,
+		buf:    make([]byte, size+aead.Overhead()),
+	}, nil
+}
+
+// Write encrypts p, writing each segment as soon as it is full
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		m := copy(e.buf[e.n:len(e.buf)-e.aead.Overhead()], p)
+		e.n += m
+		written += m
+		p = p[m:]
+
+		if e.n == len(e.buf)-e.aead.Overhead() {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close writes the last segment
+func (e *encryptWriter) Close() error {
+	if e.err != nil {
+		return e.err
+	}
+	if err := e.seal(true); err != nil {
+		return err
+	}
+	e.err = errors.New("the encrypted state is already closed")
+	return nil
+}
+
+// seal encrypts the buffered plaintext in place and writes it as the next segment
+func (e *encryptWriter) seal(last bool) error {
+	nonce, err := segmentNonce(e.prefix, e.counter, last)
+	if err != nil {
+		e.err = err
+		return err
+	}
+
+	segment := e.aead.Seal(e.buf[:0], nonce, e.buf[:e.n], e.aad)
+	if _, err := e.w.Write(segment); err != nil {
+		e.err = fmt.Errorf("failed to write encrypted data: %v", err)
+		return e.err
+	}
+
+	e.n = 0
+	e.counter++
+	return nil
+}
+
+// decryptReader opens the segments read from r
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	aad     []byte
+	prefix  []byte
+	counter uint32
+	buf     []byte // the current segment
+	out     []byte // plaintext of the current segment that was not read yet
+	last    bool
+	err     error
+}
+
+// NewDecryptReader returns a reader of the decrypted state read from r. State in the
+// segmented envelope format is decrypted as it is read, and each segment is
+// authenticated before it is returned. A reader that ends early or out of order fails
+// with an error instead of returning a shorter state. Files in the first envelope
+// version and in the legacy format are read into memory and decrypted in one piece.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	format, peeked, err := PeekFormat(br)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == FormatEncrypted {
+		header, n, err := parseEnvelopeHeader(peeked)
+		if err != nil {
+			return nil, err
+		}
+		if header.Version == envelopeVersionStream {
+			aad := bytes.Clone(peeked[:n])
+			if _, err := br.Discard(n); err != nil {
+				return nil, err
+			}
+			return newDecryptReader(br, passphrase, header, aad)
+		}
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encrypted data: %v", err)
+	}
+	plaintext, err := DecryptState(passphrase, data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(plaintext), nil
+}
+
+// PeekFormat detects the format of the state read from r without consuming it. It
+// also returns the start of the state, which holds the whole header of an encrypted
+// file.
+func PeekFormat(r *bufio.Reader) (StateFormat, []byte, error) {
+	start, err := r.Peek(maxEnvelopeHeaderLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return FormatPlaintext, nil, fmt.Errorf("failed to read state: %v", err)
+	}
+	return DetectFormat(start), start, nil
+}
+
+// newDecryptReader returns a reader of the segments that follow a parsed header
+func newDecryptReader(r io.Reader, passphrase string, header envelopeHeader, aad []byte) (*decryptReader, error) {
+	key, err := header.KDF.deriveKey(passphrase, header.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Nonce) != noncePrefixLength {
+		return nil, fmt.Errorf("invalid nonce length %d", len(header.Nonce))
+	}
+
+	d := &decryptReader{
+		r:      r,
+		aead:   aead,
+		aad:    aad,
+		prefix: header.Nonce,
+		buf:    make([]byte, int(header.ChunkSize)+aead.Overhead()),
+	}
+
+	// Open the first segment, so that a wrong passphrase is reported before the state
+	// is read
+	if err := d.open(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Read returns the decrypted state, opening the next segment when the current one is
+// consumed
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.last {
+			return 0, io.EOF
+		}
+		d.err = d.open()
+	}
+
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment. A full segment is never the last one, so
+// a short segment must be the last and a stream that ends after a full segment was cut.
+func (d *decryptReader) open() error {
+	n, err := io.ReadFull(d.r, d.buf)
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		if n < d.aead.Overhead() {
+			return fmt.Errorf("%w: the ciphertext is incomplete", ErrTruncated)
+		}
+		d.last = true
+	default:
+		return fmt.Errorf("failed to read encrypted data: %v", err)
+	}
+
+	nonce, err := segmentNonce(d.prefix, d.counter, d.last)
+	if err != nil {
+		return err
+	}
+	d.out, err = d.aead.Open(d.buf[:0], nonce, d.buf[:n], d.aad)
+	if err != nil {
+		return errDecrypt
+	}
+
+	d.counter++
+	return nil
+}
+
+// segmentNonce returns the nonce of a segment: the prefix, the 4 byte counter and the
+// last segment flag
+func segmentNonce(prefix []byte, counter uint32, last bool) ([]byte, error) {
+	if counter == ^uint32(0) {
+		return nil, errors.New("the state is too large to encrypt")
+	}
+
+	nonce := binary.BigEndian.AppendUint32(bytes.Clone(prefix), counter)
+	if last {
+		return append(nonce, lastSegmentFlag), nil
+	}
+	return append(nonce, 0), nil
+}
@@ -0,0 +1,143 @@
+package delinea
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// testChunkSize keeps the segments small, so that tests cover several of them
+const testChunkSize = 16
+
+// encryptSegments encrypts plaintext with small segments and returns the header and
+// the segments
+func encryptSegments(t *testing.T, plaintext []byte) ([]byte, [][]byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := newEncryptWriter(&buf, "passphrase", testKDF, testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted := buf.Bytes()
+	_, n, err := parseEnvelopeHeader(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var segments [][]byte
+	rest := encrypted[n:]
+	for len(rest) > 0 {
+		size := min(len(rest), testChunkSize+16)
+		segments = append(segments, rest[:size])
+		rest = rest[size:]
+	}
+	return encrypted[:n], segments
+}
+
+// decryptAll decrypts a stream with the reader and returns the state
+func decryptAll(data []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), "passphrase")
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEncryptWriterRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, testChunkSize - 1, testChunkSize, testChunkSize + 1, 3 * testChunkSize, 100} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+
+		header, segments := encryptSegments(t, plaintext)
+		if want := size/testChunkSize + 1; len(segments) != want {
+			t.Errorf("%d bytes were written in %d segments, want %d", size, len(segments), want)
+		}
+
+		encrypted := bytes.Join(append([][]byte{header}, segments...), nil)
+		decrypted, err := decryptAll(encrypted)
+		if err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("decrypted %d bytes = %d bytes, %v, want the plaintext", size, len(decrypted), err)
+		}
+		if decrypted, err := DecryptState("passphrase", encrypted); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("DecryptState() of %d bytes = %d bytes, %v, want the plaintext", size, len(decrypted), err)
+		}
+	}
+}
+
+func TestDecryptReaderRejectsModifiedStreams(t *testing.T) {
+	plaintext := make([]byte, 3*testChunkSize+5)
+	rand.Read(plaintext)
+	header, segments := encryptSegments(t, plaintext)
+	join := func(segments ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, segments...), nil)
+	}
+
+	tests := map[string][]byte{
+		"cut after a segment":  join(segments[0], segments[1]),
+		"cut in a segment":     join(segments[0], segments[1][:20]),
+		"last segment dropped": join(segments[0], segments[1], segments[2]),
+		"segment dropped":      join(segments[0], segments[2], segments[3]),
+		"segments reordered":   join(segments[1], segments[0], segments[2], segments[3]),
+		"segment repeated":     join(segments[0], segments[0], segments[1], segments[2], segments[3]),
+		"segment appended":     join(segments[0], segments[1], segments[2], segments[3], segments[3]),
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			decrypted, err := decryptAll(data)
+			if err == nil {
+				t.Fatalf("decrypted %d of %d bytes without an error", len(decrypted), len(plaintext))
+			}
+		})
+	}
+
+	// A stream that ends after a full segment is reported as truncated
+	if _, err := decryptAll(join(segments[0], segments[1])); !errors.Is(err, ErrTruncated) {
+		t.Errorf("decrypting a stream cut after a segment error = %v, want ErrTruncated", err)
+	}
+}
+
+func TestDecryptReaderWrongPassphrase(t *testing.T) {
+	header, segments := encryptSegments(t, []byte(testState))
+
+	// The first segment is opened when the reader is created
+	_, err := NewDecryptReader(bytes.NewReader(bytes.Join(append([][]byte{header}, segments...), nil)), "wrong")
+	if !errors.Is(err, errDecrypt) {
+		t.Errorf("NewDecryptReader() with the wrong passphrase error = %v, want %v", err, errDecrypt)
+	}
+}
+
+func TestDecryptFileTamperedSegment(t *testing.T) {
+	state := bytes.Repeat([]byte(testState), 5000)
+	stateFile := writeStateFile(t, state)
+	if err := EncryptFileWithOptions("passphrase", stateFile, FileOptions{KDF: testKDF}); err != nil {
+		t.Fatalf("EncryptFileWithOptions() error = %v", err)
+	}
+
+	// Damage a segment after the first, which is only detected while the file is written
+	encrypted := readStateFile(t, stateFile)
+	if len(encrypted) < 2*chunkSize {
+		t.Fatalf("encrypted file has %d bytes, want several segments", len(encrypted))
+	}
+	encrypted[len(encrypted)-100] ^= 1
+	if err := os.WriteFile(stateFile, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DecryptFile("passphrase", stateFile); !errors.Is(err, errDecrypt) {
+		t.Errorf("DecryptFile() error = %v, want %v", err, errDecrypt)
+	}
+	if !bytes.Equal(readStateFile(t, stateFile), encrypted) {
+		t.Error("DecryptFile() changed the file although it failed")
+	}
+}
@@ -4,50 +4,122 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"strconv"
 	"time"
 
 	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/ephemeralvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+// defaultRenewInterval is used when renew_interval is not configured
+const defaultRenewInterval = 5 * time.Minute
+
+// checkoutRenewRatio is the share of a secret's checkout interval after which a
+// checked out secret is renewed at the latest
+const checkoutRenewRatio = 0.8
+
 // TSSSecretResource defines the resource implementation
 type TSSSecretEphemeralResource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
 func (r *TSSSecretEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
 	resp.TypeName = "tss_secret"
 }
 
+// Ensure the ephemeral resource implementation satisfies the config validator interface
+var _ ephemeral.EphemeralResourceWithConfigValidators = &TSSSecretEphemeralResource{}
+
 // Define the model for your resource state
 type TSSSecretEphemeralResourceModel struct {
-	SecretID    types.String `tfsdk:"id"`
-	Field       types.String `tfsdk:"field"`
-	SecretValue types.String `tfsdk:"value"`
+	SecretID       types.String `tfsdk:"id"`
+	Name           types.String `tfsdk:"name"`
+	FolderPath     types.String `tfsdk:"folder_path"`
+	Search         types.String `tfsdk:"search"`
+	Field          types.String `tfsdk:"field"`
+	Checkout       types.Bool   `tfsdk:"checkout"`
+	Comment        types.String `tfsdk:"comment"`
+	TicketNumber   types.String `tfsdk:"ticket_number"`
+	TicketSystemID types.Int64  `tfsdk:"ticket_system_id"`
+	RenewInterval  types.String `tfsdk:"renew_interval"`
+	SecretValue    types.String `tfsdk:"value"`
 }
 
-// Define private data structure (optional)
+// TSSSecretPrivateData is kept by Terraform between Open, Renew and Close. It must
+// not hold the secret value itself, only its fingerprint.
 type TSSSecretPrivateData struct {
-	SecretID    string `json:"id"`
-	Field       string `json:"field"`
-	SecretValue string `json:"value"`
+	SecretID      int           `json:"id"`
+	Field         string        `json:"field"`
+	Fingerprint   string        `json:"fingerprint"`    // Fingerprint of the value returned by Open
+	RenewInterval time.Duration `json:"renew_interval"` // Time between renewals
+	CheckedOut    bool          `json:"checked_out"`    // Whether Close must check the secret in
+	Access        secretAccess  `json:"access"`         // Justification for reading the secret again
 }
 
 func (r *TSSSecretEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
-				Required:    true,
-				Description: "The ID of the secret to retrieve.",
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of the secret to retrieve. Conflicts with 'name' and 'search'; set to the ID of the found secret otherwise.",
+			},
+			"name": schema.StringAttribute{
+				Optional:    true,
+				Description: "The name of the secret to retrieve. Exactly one secret must have this name. Conflicts with 'id' and 'search'.",
+			},
+			"folder_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "The path of the folder containing the secret named by 'name', e.g. '\\Parent\\Child' or 'Parent/Child'.",
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("name")),
+				},
+			},
+			"search": schema.StringAttribute{
+				Optional:    true,
+				Description: "A search term that must match exactly one secret. Conflicts with 'id' and 'name'.",
 			},
 			"field": schema.StringAttribute{
 				Required:    true,
 				Description: "The field to extract from the secret.",
 			},
+			"checkout": schema.BoolAttribute{
+				Optional: true,
+				Description: "Whether to check the secret out before reading it. The checkout is extended on every renewal " +
+					"and the secret is checked in when Terraform closes the ephemeral resource. Required for secrets with checkout enabled.",
+			},
+			"comment": schema.StringAttribute{
+				Optional:    true,
+				Description: "A comment recorded in the audit log of the secret view and checkout. Required by secrets that require a comment to be viewed.",
+			},
+			"ticket_number": schema.StringAttribute{
+				Optional:    true,
+				Description: "A ticket number recorded in the audit log of the secret view and checkout. Required by secrets that require a ticket number to be viewed.",
+			},
+			"ticket_system_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The ID of the ticket system that 'ticket_number' belongs to, if it is not the default one.",
+				Validators: []validator.Int64{
+					int64validator.AlsoRequires(path.MatchRoot("ticket_number")),
+				},
+			},
+			"renew_interval": schema.StringAttribute{
+				Optional: true,
+				Description: fmt.Sprintf("How often the secret is read again while Terraform runs, as a duration such as \"30s\" or \"10m\". "+
+					"Terraform keeps using the value read when the resource was opened; a renewal warns if it changed. Defaults to %q.", defaultRenewInterval),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
 			"value": schema.StringAttribute{
 				Computed:    true,
 				Description: "The value of the requested field from the secret.",
@@ -56,7 +128,20 @@ func (r *TSSSecretEphemeralResource) Schema(ctx context.Context, req ephemeral.S
 	}
 }
 
+// ConfigValidators ensures exactly one way of identifying the secret is used
+func (r *TSSSecretEphemeralResource) ConfigValidators(ctx context.Context) []ephemeral.ConfigValidator {
+	return []ephemeral.ConfigValidator{
+		ephemeralvalidator.ExactlyOneOf(
+			path.MatchRoot("id"),
+			path.MatchRoot("name"),
+			path.MatchRoot("search"),
+		),
+	}
+}
+
 func (r *TSSSecretEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	ctx = withLogging(ctx, logEphemeral)
+
 	// Create a model to hold the input configuration
 	var data TSSSecretEphemeralResourceModel
 
@@ -66,41 +151,79 @@ func (r *TSSSecretEphemeralResource) Open(ctx context.Context, req ephemeral.Ope
 		return
 	}
 
-	if r.clientConfig == nil {
+	if r.client == nil {
 		resp.Diagnostics.AddError("Provider not configured", "Cannot fetch secrets because the provider is not configured.")
 		return
 	}
 
-	// Check for required fields in the model (secret_id and field)
-	if data.SecretID.IsNull() || data.Field.IsNull() {
-		resp.Diagnostics.AddError("Missing Required Field", "Both secret_id and field are required")
+	// Check for required fields in the model (field)
+	if data.Field.IsNull() {
+		resp.Diagnostics.AddError("Missing Required Field", "The field is required")
 		return
 	}
 
-	// Initialize your Delinea API client (e.g., using the secret_id and field)
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Client Creation Error", err.Error())
-		return
-	}
+	tflog.SubsystemDebug(ctx, logEphemeral, "Getting secret", map[string]interface{}{
+		"secret_id": data.SecretID.ValueString(),
+		"name":      data.Name.ValueString(),
+		"search":    data.Search.ValueString(),
+	})
 
-	// Convert SecretID to integer
-	secretID, err := strconv.Atoi(data.SecretID.ValueString())
-	if err != nil {
-		resp.Diagnostics.AddError("Invalid Secret ID", "Secret ID must be an integer")
-		return
+	lookup := secretLookup{
+		ID:         data.SecretID,
+		Name:       data.Name,
+		FolderPath: data.FolderPath,
+		Search:     data.Search,
+		Access:     newSecretAccess(data.Comment, data.TicketNumber, data.TicketSystemID),
 	}
 
-	log.Printf("[DEBUG] getting secret with id %d", secretID)
-
-	// Fetch the secret from the server using Delinea SDK
-	secret, err := client.Secret(secretID)
-	if err != nil {
-		resp.Diagnostics.AddError("Secret Fetch Error", err.Error())
-		return
+	// A secret that requires checkout can only be read once it is checked out, so
+	// it is looked up without reading it first
+	var secret *server.Secret
+	checkout := data.Checkout.ValueBool()
+	if checkout {
+		secretID, diags := lookup.resolveID(ctx, r.client)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		tflog.SubsystemDebug(ctx, logEphemeral, "Checking out secret", map[string]interface{}{"secret_id": secretID})
+
+		if err := r.client.CheckOutSecret(ctx, secretID, lookup.Access); err != nil {
+			resp.Diagnostics.AddError("Secret Check Out Error", fmt.Sprintf("Failed to check out secret %d: %s", secretID, err))
+			return
+		}
+
+		// Do not keep the secret checked out if it cannot be returned
+		defer func() {
+			if resp.Diagnostics.HasError() {
+				if err := r.client.CheckInSecret(ctx, secretID); err != nil {
+					resp.Diagnostics.AddError("Secret Check In Error", fmt.Sprintf("Failed to check in secret %d: %s", secretID, err))
+				}
+			}
+		}()
+
+		var err error
+		if secret, err = r.client.SecretWithAccess(ctx, secretID, lookup.Access); err != nil {
+			resp.Diagnostics.AddError("Secret Fetch Error", fmt.Sprintf("Failed to fetch secret: %s", err))
+			return
+		}
+	} else {
+		// Fetch the secret from the server by ID or by lookup
+		var diags diag.Diagnostics
+		secret, diags = lookup.fetch(ctx, r.client)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
 	}
+	ctx = maskSecretValues(ctx, secret)
+
+	// Renewals read the secret by the ID it resolved to
+	secretID := secret.ID
+	data.SecretID = types.StringValue(strconv.Itoa(secretID))
 
-	log.Printf("[DEBUG] using '%s' field of secret with id %d", data.Field.ValueString(), secretID)
+	tflog.SubsystemDebug(ctx, logEphemeral, "Using field of secret", map[string]interface{}{"field": data.Field.ValueString(), "secret_id": secretID})
 
 	// Extract the requested field value (assuming Field() method is available)
 	fieldValue, ok := secret.Field(data.Field.ValueString())
@@ -114,64 +237,63 @@ func (r *TSSSecretEphemeralResource) Open(ctx context.Context, req ephemeral.Ope
 
 	// Save the data into the ephemeral result state
 	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	// Set a renewal time for the resource
-	resp.RenewAt = time.Now().Add(5 * time.Minute)
+	privateData := TSSSecretPrivateData{
+		SecretID:      secretID,
+		Field:         data.Field.ValueString(),
+		Fingerprint:   r.client.fingerprint(fieldValue),
+		RenewInterval: renewInterval(data.RenewInterval),
+		CheckedOut:    checkout,
+		Access:        lookup.Access,
+	}
 
-	// Store private data for use during renewal
-	privateData, _ := json.Marshal(TSSSecretPrivateData{
-		SecretID:    data.SecretID.ValueString(),
-		Field:       data.Field.ValueString(),
-		SecretValue: data.SecretValue.ValueString(),
-	})
-	resp.Private.SetKey(ctx, "tss_secret_data", privateData)
+	// Renew early enough to extend the checkout before it ends
+	if checkout && secret.CheckOutIntervalMinutes > 0 {
+		checkoutInterval := time.Duration(secret.CheckOutIntervalMinutes) * time.Minute
+		privateData.RenewInterval = min(privateData.RenewInterval, time.Duration(float64(checkoutInterval)*checkoutRenewRatio))
+	}
+	resp.RenewAt = time.Now().Add(privateData.RenewInterval)
+	resp.Diagnostics.Append(setPrivateData(ctx, resp.Private, "tss_secret_data", privateData)...)
 }
 
+// Renew extends the checkout if Open checked the secret out, then reads the secret again
+// and warns when the value changed since Open. Terraform does not let a renewal change
+// the result, so the new value is used from the next run.
 func (r *TSSSecretEphemeralResource) Renew(ctx context.Context, req ephemeral.RenewRequest, resp *ephemeral.RenewResponse) {
-	// Retrieve the private data that was stored during Open
-	privateBytes, _ := req.Private.GetKey(ctx, "tss_secret_data")
-	if privateBytes == nil {
-		resp.Diagnostics.AddError("Missing Private Data", "Private data was not found for renewal.")
-		return
-	}
+	ctx = withLogging(ctx, logEphemeral)
 
-	// Unmarshal private data
 	var privateData TSSSecretPrivateData
-	if err := json.Unmarshal(privateBytes, &privateData); err != nil {
-		resp.Diagnostics.AddError("Invalid Private Data", "Failed to unmarshal private data.")
+	resp.Diagnostics.Append(getPrivateData(ctx, req.Private, "tss_secret_data", "renewal", &privateData)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Ensure that secret_id and field are available in the private data
-	if privateData.SecretID == "" || privateData.Field == "" {
-		resp.Diagnostics.AddError("Missing Private Data Fields", "Secret ID and field are required.")
+	if r.client == nil {
+		resp.Diagnostics.AddError("Provider not configured", "Cannot renew the secret because the provider is not configured.")
 		return
 	}
 
-	// Initialize your Delinea API client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Client Creation Error", err.Error())
-		return
-	}
+	if privateData.CheckedOut {
+		tflog.SubsystemDebug(ctx, logEphemeral, "Extending checkout of secret", map[string]interface{}{"secret_id": privateData.SecretID})
 
-	// Convert SecretID to integer
-	secretID, err := strconv.Atoi(privateData.SecretID)
-	if err != nil {
-		resp.Diagnostics.AddError("Invalid Secret ID", "Secret ID must be an integer.")
-		return
+		if err := r.client.ExtendCheckOut(ctx, privateData.SecretID); err != nil {
+			resp.Diagnostics.AddError("Secret Check Out Error", fmt.Sprintf("Failed to extend the checkout of secret %d: %s", privateData.SecretID, err))
+			return
+		}
 	}
 
-	log.Printf("[DEBUG] getting secret with id %d to renew data", secretID)
+	tflog.SubsystemDebug(ctx, logEphemeral, "Getting secret to renew data", map[string]interface{}{"secret_id": privateData.SecretID})
 
 	// Fetch the secret from the server
-	secret, err := client.Secret(secretID)
+	secret, err := r.client.SecretWithAccess(ctx, privateData.SecretID, privateData.Access)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Fetch Error", err.Error())
 		return
 	}
-
-	log.Printf("[DEBUG] using '%s' field of secret with id %d to renew data", privateData.Field, secretID)
+	ctx = maskSecretValues(ctx, secret)
 
 	// Extract the requested field value
 	fieldValue, ok := secret.Field(privateData.Field)
@@ -180,33 +302,92 @@ func (r *TSSSecretEphemeralResource) Renew(ctx context.Context, req ephemeral.Re
 		return
 	}
 
-	// Update the private data with the new secret value
-	privateData.SecretValue = fieldValue
-
-	// Store the updated private data for the next renewal
-	privateDataBytes, _ := json.Marshal(privateData)
-	resp.Private.SetKey(ctx, "tss_secret_data", privateDataBytes)
+	if fingerprint := r.client.fingerprint(fieldValue); fingerprint != privateData.Fingerprint {
+		resp.Diagnostics.AddWarning("Secret Changed", fmt.Sprintf(
+			"The field '%s' of secret %d changed after the ephemeral resource was opened. "+
+				"Terraform keeps using the value read when it was opened until the next run.", privateData.Field, privateData.SecretID))
+		privateData.Fingerprint = fingerprint
+	}
 
-	// Set the renewal time (e.g., 5 minutes from now)
-	resp.RenewAt = time.Now().Add(5 * time.Minute)
+	resp.RenewAt = time.Now().Add(privateData.RenewInterval)
+	resp.Diagnostics.Append(setPrivateData(ctx, resp.Private, "tss_secret_data", privateData)...)
 }
 
+// Close checks the secret back in if Open checked it out
 func (r *TSSSecretEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
+	ctx = withLogging(ctx, logEphemeral)
+
+	var privateData TSSSecretPrivateData
+	resp.Diagnostics.Append(getPrivateData(ctx, req.Private, "tss_secret_data", "check-in", &privateData)...)
+	if resp.Diagnostics.HasError() || !privateData.CheckedOut {
+		return
+	}
+
+	if r.client == nil {
+		resp.Diagnostics.AddError("Provider not configured", "Cannot check in the secret because the provider is not configured.")
+		return
+	}
+
+	tflog.SubsystemDebug(ctx, logEphemeral, "Checking in secret", map[string]interface{}{"secret_id": privateData.SecretID})
 
+	if err := r.client.CheckInSecret(ctx, privateData.SecretID); err != nil {
+		resp.Diagnostics.AddError("Secret Check In Error", fmt.Sprintf("Failed to check in secret %d: %s", privateData.SecretID, err))
+	}
 }
 
 func (r *TSSSecretEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
 	}
-	log.Printf("DEBUG: ProviderData received in Configure")
-	client, ok := req.ProviderData.(*server.Configuration)
+	client, ok := req.ProviderData.(*Client)
 	if !ok {
-		resp.Diagnostics.AddError("Invalid Provider Data", "Expected provider data of type *server.Configuration")
+		resp.Diagnostics.AddError("Invalid Provider Data", "Expected provider data of type *Client")
 		return
 	}
 
-	log.Printf("DEBUG: Successfully retrieved provider configuration")
+	r.client = client
+}
 
-	r.clientConfig = client
+// privateState is the private data Terraform keeps for an ephemeral resource
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+// setPrivateData stores data as JSON under key
+func setPrivateData(ctx context.Context, private privateState, key string, data interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	value, err := json.Marshal(data)
+	if err != nil {
+		diags.AddError("Invalid Private Data", fmt.Sprintf("Failed to marshal private data: %s", err))
+		return diags
+	}
+	return private.SetKey(ctx, key, value)
+}
+
+// getPrivateData reads the JSON stored under key into data. operation names what the
+// data is needed for, such as "renewal" or "check-in", in the error when it is missing.
+func getPrivateData(ctx context.Context, private privateState, key, operation string, data interface{}) diag.Diagnostics {
+	value, diags := private.GetKey(ctx, key)
+	if diags.HasError() {
+		return diags
+	}
+	if value == nil {
+		diags.AddError("Missing Private Data", fmt.Sprintf("Private data was not found for %s.", operation))
+		return diags
+	}
+	if err := json.Unmarshal(value, data); err != nil {
+		diags.AddError("Invalid Private Data", fmt.Sprintf("Failed to unmarshal private data: %s", err))
+	}
+	return diags
+}
+
+// renewInterval returns the configured renew_interval, or the default if it is not set
+func renewInterval(value types.String) time.Duration {
+	interval, err := time.ParseDuration(value.ValueString())
+	if err != nil || interval <= 0 {
+		return defaultRenewInterval
+	}
+	return interval
 }
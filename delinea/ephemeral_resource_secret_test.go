@@ -0,0 +1,268 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccEphemeralSecret(t *testing.T) {
+	f := newFakeSecretServer(t)
+	folder := f.addFolder("Prod", rootFolderID)
+	id := f.addSecret("web", folder, loginTemplateID, map[string]string{
+		"machine":  "web01.example.com",
+		"password": "s3cret!",
+	})
+
+	config := func(step, lookup string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+ephemeral "tss_secret" "test" {
+  %s
+  field = "password"
+}
+
+provider "echo" {
+  data = ephemeral.tss_secret.test.value
+}
+
+resource "echo" "%s" {}
+`, lookup, step)
+	}
+
+	// Each step uses a new echo resource, as echo does not update its data in place
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccEchoProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config:      config("missing", `search = "nothing"`),
+				ExpectError: regexp.MustCompile(`no secret matching 'nothing' was found`),
+			},
+			{
+				Config: config("by_id", fmt.Sprintf(`id = "%d"`, id)),
+				Check:  resource.TestCheckResourceAttr("echo.by_id", "data", "s3cret!"),
+			},
+			{
+				Config: config("by_name", `
+  name        = "web"
+  folder_path = "Prod"`),
+				Check: resource.TestCheckResourceAttr("echo.by_name", "data", "s3cret!"),
+			},
+			{
+				Config: config("by_search", `search = "web01"`),
+				Check:  resource.TestCheckResourceAttr("echo.by_search", "data", "s3cret!"),
+			},
+		},
+	})
+}
+
+func TestEphemeralSecretRenewAndClose(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"password": "s3cret!"})
+	providerServer := newTestProviderServer(t, f)
+	ctx := context.Background()
+
+	opened, err := providerServer.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: "tss_secret",
+		Config: testEphemeralConfig(t, providerServer, "tss_secret", map[string]tftypes.Value{
+			"id":             tftypes.NewValue(tftypes.String, strconv.Itoa(id)),
+			"field":          tftypes.NewValue(tftypes.String, "password"),
+			"renew_interval": tftypes.NewValue(tftypes.String, "90s"),
+		}),
+	})
+	if err != nil || len(opened.Diagnostics) > 0 {
+		t.Fatalf("OpenEphemeralResource() = %v, %v", diagnosticSummaries(opened.Diagnostics), err)
+	}
+	if until := time.Until(opened.RenewAt); until < 80*time.Second || until > 90*time.Second {
+		t.Errorf("RenewAt = %v, want in 90s", opened.RenewAt)
+	}
+	if strings.Contains(string(opened.Private), "s3cret!") {
+		t.Errorf("private data %s contains the secret value", opened.Private)
+	}
+
+	renew := func() *tfprotov6.RenewEphemeralResourceResponse {
+		t.Helper()
+		renewed, err := providerServer.RenewEphemeralResource(ctx, &tfprotov6.RenewEphemeralResourceRequest{
+			TypeName: "tss_secret",
+			Private:  opened.Private,
+		})
+		if err != nil {
+			t.Fatalf("RenewEphemeralResource() error = %v", err)
+		}
+		opened.Private = renewed.Private
+		return renewed
+	}
+
+	if renewed := renew(); len(renewed.Diagnostics) > 0 || renewed.RenewAt.IsZero() {
+		t.Errorf("RenewEphemeralResource() = %v, %v, want a renewal time and no diagnostics", renewed.RenewAt, diagnosticSummaries(renewed.Diagnostics))
+	}
+
+	f.setFieldValue(id, "password", "rotated")
+	if got := diagnosticSummaries(renew().Diagnostics); !slices.Equal(got, []string{"WARNING: Secret Changed"}) {
+		t.Errorf("RenewEphemeralResource() after a change = %v, want a warning", got)
+	}
+	if got := diagnosticSummaries(renew().Diagnostics); len(got) > 0 {
+		t.Errorf("RenewEphemeralResource() after a reported change = %v, want no diagnostics", got)
+	}
+
+	closed, err := providerServer.CloseEphemeralResource(ctx, &tfprotov6.CloseEphemeralResourceRequest{
+		TypeName: "tss_secret",
+		Private:  opened.Private,
+	})
+	if err != nil || len(closed.Diagnostics) > 0 {
+		t.Fatalf("CloseEphemeralResource() = %v, %v", diagnosticSummaries(closed.Diagnostics), err)
+	}
+	if got := f.requestCount(fmt.Sprintf("POST /api/v1/secrets/%d/check-in", id)); got != 0 {
+		t.Errorf("check-in requests = %d, want 0 for a secret that was not checked out", got)
+	}
+}
+
+func TestEphemeralSecretMissingPrivateData(t *testing.T) {
+	f := newFakeSecretServer(t)
+	providerServer := newTestProviderServer(t, f)
+	ctx := context.Background()
+
+	renewed, err := providerServer.RenewEphemeralResource(ctx, &tfprotov6.RenewEphemeralResourceRequest{TypeName: "tss_secret"})
+	if err != nil || len(renewed.Diagnostics) != 1 || renewed.Diagnostics[0].Detail != "Private data was not found for renewal." {
+		t.Errorf("RenewEphemeralResource() = %v, %v, want a missing private data error", diagnosticSummaries(renewed.Diagnostics), err)
+	}
+	closed, err := providerServer.CloseEphemeralResource(ctx, &tfprotov6.CloseEphemeralResourceRequest{TypeName: "tss_secret"})
+	if err != nil || len(closed.Diagnostics) != 1 || closed.Diagnostics[0].Detail != "Private data was not found for check-in." {
+		t.Errorf("CloseEphemeralResource() = %v, %v, want a missing private data error", diagnosticSummaries(closed.Diagnostics), err)
+	}
+}
+
+func TestEphemeralSecretCheckout(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("admin", rootFolderID, loginTemplateID, map[string]string{"password": "s3cret!"})
+	f.enableCheckout(id, 10)
+	providerServer := newTestProviderServer(t, f)
+	ctx := context.Background()
+
+	open := func(values map[string]tftypes.Value) *tfprotov6.OpenEphemeralResourceResponse {
+		t.Helper()
+		values["name"] = tftypes.NewValue(tftypes.String, "admin")
+		opened, err := providerServer.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+			TypeName: "tss_secret",
+			Config:   testEphemeralConfig(t, providerServer, "tss_secret", values),
+		})
+		if err != nil {
+			t.Fatalf("OpenEphemeralResource() error = %v", err)
+		}
+		return opened
+	}
+
+	// Without checkout the secret cannot be read
+	opened := open(map[string]tftypes.Value{"field": tftypes.NewValue(tftypes.String, "password")})
+	if got := diagnosticSummaries(opened.Diagnostics); !slices.Equal(got, []string{"ERROR: Secret Fetch Error"}) {
+		t.Fatalf("OpenEphemeralResource() without checkout = %v, want a fetch error", got)
+	}
+
+	// A missing field fails, and the secret is checked in again
+	opened = open(map[string]tftypes.Value{
+		"field":    tftypes.NewValue(tftypes.String, "api-key"),
+		"checkout": tftypes.NewValue(tftypes.Bool, true),
+	})
+	if got := diagnosticSummaries(opened.Diagnostics); !slices.Equal(got, []string{"ERROR: Field Not Found"}) || f.checkedOut(id) {
+		t.Fatalf("OpenEphemeralResource() with a missing field = %v, checked out %t, want an error and no checkout", got, f.checkedOut(id))
+	}
+
+	opened = open(map[string]tftypes.Value{
+		"field":          tftypes.NewValue(tftypes.String, "password"),
+		"checkout":       tftypes.NewValue(tftypes.Bool, true),
+		"comment":        tftypes.NewValue(tftypes.String, "deploying web"),
+		"renew_interval": tftypes.NewValue(tftypes.String, "1h"),
+	})
+	if len(opened.Diagnostics) > 0 {
+		t.Fatalf("OpenEphemeralResource() = %v", diagnosticSummaries(opened.Diagnostics))
+	}
+	if !f.checkedOut(id) {
+		t.Error("secret is not checked out after Open")
+	}
+	if got := f.checkoutComments(id); !slices.Equal(got, []string{"", "deploying web"}) {
+		t.Errorf("checkout comments = %q, want the comment of the second checkout", got)
+	}
+	// The renewal must come before the 10 minute checkout ends, not after renew_interval
+	if until := time.Until(opened.RenewAt); until < 7*time.Minute || until > 8*time.Minute {
+		t.Errorf("RenewAt in %s, want in 8m", until)
+	}
+
+	renewed, err := providerServer.RenewEphemeralResource(ctx, &tfprotov6.RenewEphemeralResourceRequest{
+		TypeName: "tss_secret",
+		Private:  opened.Private,
+	})
+	if err != nil || len(renewed.Diagnostics) > 0 {
+		t.Fatalf("RenewEphemeralResource() = %v, %v", diagnosticSummaries(renewed.Diagnostics), err)
+	}
+	if got := f.requestCount(fmt.Sprintf("POST /api/v1/secrets/%d/extend-check-out", id)); got != 1 {
+		t.Errorf("checkout extensions = %d, want 1", got)
+	}
+
+	closed, err := providerServer.CloseEphemeralResource(ctx, &tfprotov6.CloseEphemeralResourceRequest{
+		TypeName: "tss_secret",
+		Private:  renewed.Private,
+	})
+	if err != nil || len(closed.Diagnostics) > 0 {
+		t.Fatalf("CloseEphemeralResource() = %v, %v", diagnosticSummaries(closed.Diagnostics), err)
+	}
+	if f.checkedOut(id) {
+		t.Error("secret is still checked out after Close")
+	}
+}
+
+func TestAccEphemeralSecret_checkout(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("admin", rootFolderID, loginTemplateID, map[string]string{"password": "s3cret!"})
+	f.enableCheckout(id, 10)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccEchoProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+ephemeral "tss_secret" "test" {
+  id       = "%d"
+  field    = "password"
+  checkout = true
+  comment  = "terraform apply"
+}
+
+provider "echo" {
+  data = ephemeral.tss_secret.test.value
+}
+
+resource "echo" "test" {}
+`, id),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("echo.test", "data", "s3cret!"),
+					func(*terraform.State) error {
+						// Terraform closes the ephemeral resource at the end of every run
+						if f.checkedOut(id) {
+							return fmt.Errorf("secret %d is still checked out", id)
+						}
+						if comments := f.checkoutComments(id); len(comments) == 0 || comments[0] != "terraform apply" {
+							return fmt.Errorf("checkout comments = %q, want the configured comment", comments)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
@@ -2,31 +2,49 @@ package delinea
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/ephemeralvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // TSSSecretResource defines the resource implementation
 type TSSSecretsEphemeralResource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
 func (r *TSSSecretsEphemeralResource) Metadata(ctx context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
 	resp.TypeName = "tss_secrets"
 }
 
+// Ensure the ephemeral resource implementation satisfies the config validator interface
+var _ ephemeral.EphemeralResourceWithConfigValidators = &TSSSecretsEphemeralResource{}
+
 // Define the model for your resource state
 type TSSSecretsEphemeralResourceModel struct {
-	IDs     []types.Int64 `tfsdk:"ids"`
-	Field   types.String  `tfsdk:"field"`
-	Secrets []SecretModel `tfsdk:"secrets"`
+	IDs            []types.Int64           `tfsdk:"ids"`
+	Names          []types.String          `tfsdk:"names"`
+	FolderPath     types.String            `tfsdk:"folder_path"`
+	Searches       []types.String          `tfsdk:"searches"`
+	Field          types.String            `tfsdk:"field"`
+	RenewInterval  types.String            `tfsdk:"renew_interval"`
+	Comment        types.String            `tfsdk:"comment"`
+	TicketNumber   types.String            `tfsdk:"ticket_number"`
+	TicketSystemID types.Int64             `tfsdk:"ticket_system_id"`
+	OnError        types.String            `tfsdk:"on_error"`
+	Secrets        []SecretModel           `tfsdk:"secrets"`
+	ByID           map[string]types.String `tfsdk:"by_id"`
+	Errors         map[string]types.String `tfsdk:"errors"`
 }
 
 type SecretModel struct {
@@ -34,11 +52,14 @@ type SecretModel struct {
 	Value types.String `tfsdk:"value"`
 }
 
-// Define private data structure (optional)
+// TSSSecretsPrivateData is kept by Terraform between Open and Renew. It must not hold
+// the secret values themselves, only their fingerprints.
 type TSSSecretsPrivateData struct {
-	IDs     []types.Int64 `tfsdk:"ids"`
-	Field   string        `json:"field"`
-	Secrets []SecretModel `tfsdk:"secrets"`
+	IDs           []int          `json:"ids"` // IDs of the secrets whose values were returned by Open
+	Field         string         `json:"field"`
+	Fingerprints  map[int]string `json:"fingerprints"`   // Fingerprints of the returned values by secret ID
+	RenewInterval time.Duration  `json:"renew_interval"` // Time between renewals
+	Access        secretAccess   `json:"access"`         // Justification for reading the secrets again
 }
 
 func (r *TSSSecretsEphemeralResource) Schema(ctx context.Context, req ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
@@ -46,13 +67,61 @@ func (r *TSSSecretsEphemeralResource) Schema(ctx context.Context, req ephemeral.
 		Attributes: map[string]schema.Attribute{
 			"ids": schema.ListAttribute{
 				ElementType: types.Int64Type,
-				Required:    true,
-				Description: "A list of IDs of the secrets",
+				Optional:    true,
+				Computed:    true,
+				Description: "A list of IDs of the secrets. Conflicts with 'names' and 'searches'; set to the IDs of the found secrets otherwise",
+			},
+			"names": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "A list of secret names, each of which must match exactly one secret. Conflicts with 'ids' and 'searches'",
+			},
+			"folder_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "The path of the folder containing the secrets named by 'names', e.g. '\\Parent\\Child' or 'Parent/Child'",
+				Validators: []validator.String{
+					stringvalidator.AlsoRequires(path.MatchRoot("names")),
+				},
+			},
+			"searches": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "A list of search terms, each of which must match exactly one secret. Conflicts with 'ids' and 'names'",
 			},
 			"field": schema.StringAttribute{
 				Required:    true,
 				Description: "The field to extract from the secrets",
 			},
+			"renew_interval": schema.StringAttribute{
+				Optional: true,
+				Description: fmt.Sprintf("How often the secrets are read again while Terraform runs, as a duration such as \"30s\" or \"10m\". "+
+					"Terraform keeps using the values read when the resource was opened; a renewal warns if they changed. Defaults to %q.", defaultRenewInterval),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"comment": schema.StringAttribute{
+				Optional:    true,
+				Description: "A comment recorded in the audit log of the secret views. Required by secrets that require a comment to be viewed.",
+			},
+			"ticket_number": schema.StringAttribute{
+				Optional:    true,
+				Description: "A ticket number recorded in the audit log of the secret views. Required by secrets that require a ticket number to be viewed.",
+			},
+			"ticket_system_id": schema.Int64Attribute{
+				Optional:    true,
+				Description: "The ID of the ticket system that 'ticket_number' belongs to, if it is not the default one.",
+				Validators: []validator.Int64{
+					int64validator.AlsoRequires(path.MatchRoot("ticket_number")),
+				},
+			},
+			"on_error": schema.StringAttribute{
+				Optional:    true,
+				Description: "What to do when a secret cannot be fetched or does not have the field: 'fail' fails, 'skip' leaves the secret out of 'secrets' and 'null' keeps its position with a null value. Failures are listed in 'errors' unless the read fails. When not set, secrets that cannot be fetched are skipped with a warning and a secret without the field fails, as in earlier versions",
+				Validators: []validator.String{
+					stringvalidator.OneOf(onErrorValues...),
+				},
+			},
 			"secrets": schema.ListNestedAttribute{
 				Computed:    true,
 				Description: "A list of secrets with their field values",
@@ -69,11 +138,34 @@ func (r *TSSSecretsEphemeralResource) Schema(ctx context.Context, req ephemeral.
 					},
 				},
 			},
+			"by_id": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The field values of the fetched secrets by secret ID, which do not depend on the order of the results",
+			},
+			"errors": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "The reasons why secrets could not be fetched, by secret ID, or by name or search term when the lookup itself failed",
+			},
 		},
 	}
 }
 
+// ConfigValidators ensures exactly one way of identifying the secrets is used
+func (r *TSSSecretsEphemeralResource) ConfigValidators(ctx context.Context) []ephemeral.ConfigValidator {
+	return []ephemeral.ConfigValidator{
+		ephemeralvalidator.ExactlyOneOf(
+			path.MatchRoot("ids"),
+			path.MatchRoot("names"),
+			path.MatchRoot("searches"),
+		),
+	}
+}
+
 func (r *TSSSecretsEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	ctx = withLogging(ctx, logEphemeral)
+
 	// Create a model to hold the input configuration
 	var data TSSSecretsEphemeralResourceModel
 
@@ -83,165 +175,141 @@ func (r *TSSSecretsEphemeralResource) Open(ctx context.Context, req ephemeral.Op
 		return
 	}
 
-	if r.clientConfig == nil {
+	if r.client == nil {
 		resp.Diagnostics.AddError("Provider not configured", "Cannot fetch secrets because the provider is not configured.")
 		return
 	}
 
-	// Check for required fields in the model (secret_ids and field)
-	if data.IDs == nil || len(data.IDs) == 0 || data.Field.IsNull() {
-		resp.Diagnostics.AddError("Missing Required Field", "Both secret_ids and field are required")
+	// Check for required fields in the model (secret_ids, names or searches, and field)
+	if len(data.IDs) == 0 && len(data.Names) == 0 && len(data.Searches) == 0 || data.Field.IsNull() {
+		resp.Diagnostics.AddError("Missing Required Field", "Both secret_ids (or names or searches) and field are required")
 		return
 	}
 
-	// Initialize your Delinea API client (e.g., using the secret_id and field)
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Client Creation Error", err.Error())
+	tflog.SubsystemDebug(ctx, logEphemeral, "Getting secrets", map[string]interface{}{
+		"secret_ids": len(data.IDs),
+		"names":      len(data.Names),
+		"searches":   len(data.Searches),
+	})
+
+	// Fetch the secrets concurrently and extract the field; the results keep the order of the input
+	result, secrets, diags := secretsLookup{
+		IDs:        data.IDs,
+		Names:      data.Names,
+		FolderPath: data.FolderPath,
+		Searches:   data.Searches,
+		Access:     newSecretAccess(data.Comment, data.TicketNumber, data.TicketSystemID),
+	}.fetchField(ctx, r.client, data.Field.ValueString(), data.OnError.ValueString())
+	ctx = maskSecretValues(ctx, secrets...)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Fetch secrets
-	var results []SecretModel
-
-	for _, id := range data.IDs {
-		secretID := int(id.ValueInt64())
-
-		log.Printf("[DEBUG] getting secret with id %d", secretID)
-
-		// Fetch the secret
-		secret, err := client.Secret(secretID)
-		if err != nil {
-			resp.Diagnostics.AddWarning("Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret with ID %d: %s", secretID, err))
-			continue // Skip this ID and continue with the rest
-		}
+	data.Secrets = result.Secrets
+	data.ByID = result.ByID
+	data.Errors = result.Errors
 
-		log.Printf("[DEBUG] using '%s' field of secret with id %d", data.Field.ValueString(), secretID)
-
-		// Extract the requested field value (assuming Field() method is available)
-		fieldValue, ok := secret.Field(data.Field.ValueString())
-		if !ok {
-			resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("Field %s not found in the secret", data.Field.ValueString()))
-			continue
-		}
-
-		// Save the secret value in the state
-		results = append(results, struct {
-			ID    types.Int64  `tfsdk:"id"`
-			Value types.String `tfsdk:"value"`
-		}{
-			ID:    types.Int64Value(int64(secretID)),
-			Value: types.StringValue(fieldValue),
-		})
+	// Secrets looked up by name or search term report the IDs they resolved to,
+	// which are also the IDs read again on renewal
+	if data.IDs == nil {
+		data.IDs = result.IDs
 	}
 
-	// Set the secret value in the result
-	data.Secrets = results
-
 	// Save the data into the ephemeral result state
 	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	// Set a renewal time for the resource
-	resp.RenewAt = time.Now().Add(5 * time.Minute)
-
-	// Store private data for use during renewal
-	privateData, _ := json.Marshal(TSSSecretsPrivateData{
-		IDs:     data.IDs,
-		Field:   data.Field.ValueString(),
-		Secrets: data.Secrets,
-	})
-	resp.Private.SetKey(ctx, "tss_secrets_data", privateData)
+	// Renewals read the secrets whose values were returned again
+	privateData := TSSSecretsPrivateData{
+		Field:         data.Field.ValueString(),
+		Fingerprints:  map[int]string{},
+		RenewInterval: renewInterval(data.RenewInterval),
+		Access:        newSecretAccess(data.Comment, data.TicketNumber, data.TicketSystemID),
+	}
+	for _, secret := range result.Secrets {
+		if secret.ID.IsNull() || secret.Value.IsNull() {
+			continue
+		}
+		id := int(secret.ID.ValueInt64())
+		privateData.IDs = append(privateData.IDs, id)
+		privateData.Fingerprints[id] = r.client.fingerprint(secret.Value.ValueString())
+	}
+	resp.RenewAt = time.Now().Add(privateData.RenewInterval)
+	resp.Diagnostics.Append(setPrivateData(ctx, resp.Private, "tss_secrets_data", privateData)...)
 }
 
+// Renew reads the secrets again and warns about those whose value changed since Open.
+// Terraform does not let a renewal change the result, so new values are used from the
+// next run.
 func (r *TSSSecretsEphemeralResource) Renew(ctx context.Context, req ephemeral.RenewRequest, resp *ephemeral.RenewResponse) {
-	// Retrieve the private data that was stored during Open
-	privateBytes, _ := req.Private.GetKey(ctx, "tss_secrets_data")
-	if privateBytes == nil {
-		resp.Diagnostics.AddError("Missing Private Data", "Private data was not found for renewal.")
-		return
-	}
+	ctx = withLogging(ctx, logEphemeral)
 
-	// Unmarshal private data
 	var privateData TSSSecretsPrivateData
-	if err := json.Unmarshal(privateBytes, &privateData); err != nil {
-		resp.Diagnostics.AddError("Invalid Private Data", "Failed to unmarshal private data.")
-		return
-	}
-
-	// Ensure that secret_id and field are available in the private data
-	if privateData.IDs == nil || len(privateData.IDs) == 0 || privateData.Field == "" {
-		resp.Diagnostics.AddError("Missing Private Data Fields", "Secret ID and field are required.")
+	resp.Diagnostics.Append(getPrivateData(ctx, req.Private, "tss_secrets_data", "renewal", &privateData)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	// Initialize your Delinea API client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Client Creation Error", err.Error())
+	if r.client == nil {
+		resp.Diagnostics.AddError("Provider not configured", "Cannot renew the secrets because the provider is not configured.")
 		return
 	}
 
-	// Fetch secrets
-	var results []SecretModel
-
-	for _, id := range privateData.IDs {
-		secretID := int(id.ValueInt64())
+	tflog.SubsystemDebug(ctx, logEphemeral, "Getting secrets to renew data", map[string]interface{}{"secret_ids": privateData.IDs})
 
-		log.Printf("[DEBUG] getting secret with id %d to renew data", secretID)
+	// Fetch the secrets concurrently; the results keep the order of the IDs
+	lookup := secretsLookup{IDs: make([]types.Int64, len(privateData.IDs)), Access: privateData.Access}
+	for i, id := range privateData.IDs {
+		lookup.IDs[i] = types.Int64Value(int64(id))
+	}
+	secrets, errs := lookup.fetch(ctx, r.client)
+	ctx = maskSecretValues(ctx, secrets...)
 
-		// Fetch the secret
-		secret, err := client.Secret(secretID)
+	var changed []string
+	for i, secretID := range privateData.IDs {
+		secret, err := secrets[i], errs[i]
 		if err != nil {
 			resp.Diagnostics.AddWarning("Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret with ID %d: %s", secretID, err))
-			continue // Skip this ID and continue with the rest
+			continue
 		}
 
-		log.Printf("[DEBUG] using '%s' field of secret with id %d to renew data", privateData.Field, secretID)
-
-		// Extract the requested field value (assuming Field() method is available)
 		fieldValue, ok := secret.Field(privateData.Field)
 		if !ok {
-			resp.Diagnostics.AddError("Field Not Found", fmt.Sprintf("Field %s not found in the secret", privateData.Field))
+			resp.Diagnostics.AddWarning("Field Not Found", fmt.Sprintf("Field %s not found in secret %d", privateData.Field, secretID))
 			continue
 		}
 
-		// Save the secret value in the state
-		results = append(results, struct {
-			ID    types.Int64  `tfsdk:"id"`
-			Value types.String `tfsdk:"value"`
-		}{
-			ID:    types.Int64Value(int64(secretID)),
-			Value: types.StringValue(fieldValue),
-		})
+		if fingerprint := r.client.fingerprint(fieldValue); fingerprint != privateData.Fingerprints[secretID] {
+			changed = append(changed, strconv.Itoa(secretID))
+			privateData.Fingerprints[secretID] = fingerprint
+		}
+	}
+	if len(changed) > 0 {
+		resp.Diagnostics.AddWarning("Secrets Changed", fmt.Sprintf(
+			"The field '%s' of secrets %s changed after the ephemeral resource was opened. "+
+				"Terraform keeps using the values read when it was opened until the next run.", privateData.Field, strings.Join(changed, ", ")))
 	}
 
-	// Update the private data with the new secret value
-	privateData.Secrets = results
-
-	// Store the updated private data for the next renewal
-	privateDataBytes, _ := json.Marshal(privateData)
-	resp.Private.SetKey(ctx, "tss_secrets_data", privateDataBytes)
-
-	// Set the renewal time (e.g., 5 minutes from now)
-	resp.RenewAt = time.Now().Add(5 * time.Minute)
+	resp.RenewAt = time.Now().Add(privateData.RenewInterval)
+	resp.Diagnostics.Append(setPrivateData(ctx, resp.Private, "tss_secrets_data", privateData)...)
 }
 
+// Close has nothing to release, as the secrets are only read
 func (r *TSSSecretsEphemeralResource) Close(ctx context.Context, req ephemeral.CloseRequest, resp *ephemeral.CloseResponse) {
-
 }
 
 func (r *TSSSecretsEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
 	}
-	log.Printf("DEBUG: ProviderData received in Configure: %+v\n", req.ProviderData)
-	client, ok := req.ProviderData.(*server.Configuration)
+	client, ok := req.ProviderData.(*Client)
 	if !ok {
-		resp.Diagnostics.AddError("Invalid Provider Data", "Expected provider data of type *server.Configuration")
+		resp.Diagnostics.AddError("Invalid Provider Data", "Expected provider data of type *Client")
 		return
 	}
 
-	log.Printf("DEBUG: Successfully retrieved provider configuration")
-
-	r.clientConfig = client
+	r.client = client
 }
@@ -0,0 +1,128 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccEphemeralSecrets(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"password": "web-s3cret"})
+	db := f.addSecret("db", rootFolderID, loginTemplateID, map[string]string{"password": "db-s3cret"})
+	vault := f.addSecret("vault", rootFolderID, loginTemplateID, map[string]string{"password": "vault-s3cret"})
+	f.requireComment(vault)
+
+	config := func(step, lookup string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+ephemeral "tss_secrets" "test" {
+  %s
+  field = "password"
+}
+
+provider "echo" {
+  data = ephemeral.tss_secrets.test.secrets
+}
+
+resource "echo" "%s" {}
+`, lookup, step)
+	}
+
+	// Each step uses a new echo resource, as echo does not update its data in place
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccEchoProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("by_ids", fmt.Sprintf(`ids = [%d, %d]`, db, web)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("echo.by_ids", "data.#", "2"),
+					resource.TestCheckResourceAttr("echo.by_ids", "data.0.id", strconv.Itoa(db)),
+					resource.TestCheckResourceAttr("echo.by_ids", "data.0.value", "db-s3cret"),
+					resource.TestCheckResourceAttr("echo.by_ids", "data.1.id", strconv.Itoa(web)),
+					resource.TestCheckResourceAttr("echo.by_ids", "data.1.value", "web-s3cret"),
+				),
+			},
+			{
+				Config: config("by_names", `names = ["web"]`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("echo.by_names", "data.#", "1"),
+					resource.TestCheckResourceAttr("echo.by_names", "data.0.id", strconv.Itoa(web)),
+					resource.TestCheckResourceAttr("echo.by_names", "data.0.value", "web-s3cret"),
+				),
+			},
+			{
+				Config: config("skip_missing", fmt.Sprintf(`
+  ids      = [9999, %d]
+  on_error = "skip"`, web)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("echo.skip_missing", "data.#", "1"),
+					resource.TestCheckResourceAttr("echo.skip_missing", "data.0.value", "web-s3cret"),
+				),
+			},
+			{
+				Config: config("with_comment", fmt.Sprintf(`
+  ids     = [%d, %d]
+  comment = "terraform apply"`, vault, web)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("echo.with_comment", "data.#", "2"),
+					resource.TestCheckResourceAttr("echo.with_comment", "data.0.value", "vault-s3cret"),
+					resource.TestCheckResourceAttr("echo.with_comment", "data.1.value", "web-s3cret"),
+				),
+			},
+		},
+	})
+}
+
+func TestEphemeralSecretsRenew(t *testing.T) {
+	f := newFakeSecretServer(t)
+	web := f.addSecret("web", rootFolderID, loginTemplateID, map[string]string{"password": "web-s3cret"})
+	db := f.addSecret("db", rootFolderID, loginTemplateID, map[string]string{"password": "db-s3cret"})
+	providerServer := newTestProviderServer(t, f)
+	ctx := context.Background()
+
+	opened, err := providerServer.OpenEphemeralResource(ctx, &tfprotov6.OpenEphemeralResourceRequest{
+		TypeName: "tss_secrets",
+		Config: testEphemeralConfig(t, providerServer, "tss_secrets", map[string]tftypes.Value{
+			"names": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+				tftypes.NewValue(tftypes.String, "web"),
+				tftypes.NewValue(tftypes.String, "db"),
+			}),
+			"field": tftypes.NewValue(tftypes.String, "password"),
+		}),
+	})
+	if err != nil || len(opened.Diagnostics) > 0 {
+		t.Fatalf("OpenEphemeralResource() = %v, %v", diagnosticSummaries(opened.Diagnostics), err)
+	}
+	if strings.Contains(string(opened.Private), "s3cret") {
+		t.Errorf("private data %s contains secret values", opened.Private)
+	}
+
+	f.setFieldValue(db, "password", "rotated")
+	renewed, err := providerServer.RenewEphemeralResource(ctx, &tfprotov6.RenewEphemeralResourceRequest{
+		TypeName: "tss_secrets",
+		Private:  opened.Private,
+	})
+	if err != nil {
+		t.Fatalf("RenewEphemeralResource() error = %v", err)
+	}
+	if got := diagnosticSummaries(renewed.Diagnostics); !slices.Equal(got, []string{"WARNING: Secrets Changed"}) {
+		t.Fatalf("RenewEphemeralResource() = %v, want a warning", got)
+	}
+	if detail := renewed.Diagnostics[0].Detail; !strings.Contains(detail, strconv.Itoa(db)) || strings.Contains(detail, strconv.Itoa(web)) {
+		t.Errorf("warning %q, want only secret %d", detail, db)
+	}
+	if got := f.requestCount(fmt.Sprintf("GET /api/v1/secrets/%d", web)); got != 2 {
+		t.Errorf("reads of secret %d = %d, want 2", web, got)
+	}
+}
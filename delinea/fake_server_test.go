@@ -0,0 +1,1057 @@
+package delinea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+)
+
+// Credentials accepted by the fake Secret Server
+const (
+	fakeUsername = "terraform"
+	fakePassword = "fake-password"
+)
+
+// Secret templates of the fake Secret Server
+const (
+	loginTemplateID = 6001 // Machine, Username, Password and Notes
+	fileTemplateID  = 6002 // Username, a file field and a passphrase
+	sshTemplateID   = 6003 // Machine, Username, SSH key files and their passphrase
+)
+
+// fileNotForDisplay is what Secret Server returns as the item value of a file field
+const fileNotForDisplay = "*** Not Valid For Display ***"
+
+// fakeFile is a file attached to a secret field
+type fakeFile struct {
+	ID      int // Attachment ID, new for every upload
+	Name    string
+	Content []byte
+}
+
+// fakeSecretServer is an in-process Secret Server for tests. It keeps secrets,
+// templates, folders and file attachments in memory and serves the parts of the
+// REST API used by the provider and the SDK, so tests never need a live vault.
+type fakeSecretServer struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	tokens        map[string]bool // Access tokens that are currently valid
+	tokenRequests int
+	requests      []string         // Method and path of every API request, in order
+	failures      map[string][]int // Status codes to answer with next, by method and path
+	retryAfter    string           // Retry-After header of injected failures
+	latency       time.Duration    // Delay of every API response
+	inFlight      int              // API requests being handled
+	maxInFlight   int              // Highest number of API requests handled at the same time
+	nextID        int
+
+	secrets     map[int]*server.Secret
+	files       map[int]map[string]fakeFile // Attachments by secret ID and field slug
+	templates   map[int]*server.SecretTemplate
+	folders     map[int]*Folder
+	comments    map[int][]string       // Comments of the checkouts of each secret, in order
+	views       map[int][]secretAccess // Justifications given to view each secret, in order
+	deactivated map[int]bool           // Secrets deactivated by deactivateSecret, which cannot be viewed
+}
+
+// newFakeSecretServer starts a fake Secret Server with the test templates and no
+// secrets. It is stopped when the test ends.
+func newFakeSecretServer(t *testing.T) *fakeSecretServer {
+	t.Helper()
+
+	f := &fakeSecretServer{
+		tokens:      map[string]bool{},
+		failures:    map[string][]int{},
+		nextID:      100,
+		secrets:     map[int]*server.Secret{},
+		comments:    map[int][]string{},
+		views:       map[int][]secretAccess{},
+		deactivated: map[int]bool{},
+		files:       map[int]map[string]fakeFile{},
+		templates:   map[int]*server.SecretTemplate{},
+		folders:     map[int]*Folder{},
+	}
+
+	f.templates[loginTemplateID] = &server.SecretTemplate{
+		ID:   loginTemplateID,
+		Name: "Test Login",
+		Fields: []server.SecretTemplateField{
+			{SecretTemplateFieldID: 11, Name: "Machine", FieldSlugName: "machine", DisplayName: "Machine"},
+			{SecretTemplateFieldID: 12, Name: "Username", FieldSlugName: "username", DisplayName: "Username", IsRequired: true},
+			{SecretTemplateFieldID: 13, Name: "Password", FieldSlugName: "password", DisplayName: "Password", IsPassword: true},
+			{SecretTemplateFieldID: 14, Name: "Notes", FieldSlugName: "notes", DisplayName: "Notes", IsNotes: true},
+		},
+	}
+	f.templates[fileTemplateID] = &server.SecretTemplate{
+		ID:   fileTemplateID,
+		Name: "Test Certificate",
+		Fields: []server.SecretTemplateField{
+			{SecretTemplateFieldID: 21, Name: "Username", FieldSlugName: "username", DisplayName: "Username"},
+			{SecretTemplateFieldID: 22, Name: "Certificate", FieldSlugName: "certificate", DisplayName: "Certificate", IsFile: true},
+			{SecretTemplateFieldID: 23, Name: "Passphrase", FieldSlugName: "passphrase", DisplayName: "Passphrase", IsPassword: true},
+		},
+	}
+	f.templates[sshTemplateID] = &server.SecretTemplate{
+		ID:   sshTemplateID,
+		Name: "Test SSH Key",
+		Fields: []server.SecretTemplateField{
+			{SecretTemplateFieldID: 31, Name: "Machine", FieldSlugName: "machine", DisplayName: "Machine"},
+			{SecretTemplateFieldID: 32, Name: "Username", FieldSlugName: "username", DisplayName: "Username"},
+			{SecretTemplateFieldID: 33, Name: "Private Key", FieldSlugName: "private-key", DisplayName: "Private Key", IsFile: true},
+			{SecretTemplateFieldID: 34, Name: "Public Key", FieldSlugName: "public-key", DisplayName: "Public Key", IsFile: true},
+			{SecretTemplateFieldID: 35, Name: "Private Key Passphrase", FieldSlugName: "private-key-passphrase", DisplayName: "Private Key Passphrase", IsPassword: true},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthcheck.aspx", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, server.Response{Healthy: true})
+	})
+	mux.HandleFunc("POST /oauth2/token", f.handleToken)
+
+	api := func(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" /api/v1"+path, f.authenticated(handler))
+	}
+	api("GET /secrets", f.handleSearchSecrets)
+	api("POST /secrets/{$}", f.handleWriteSecret)
+	api("GET /secrets/{id}", f.handleGetSecret)
+	api("GET /secrets/{id}/summary", f.handleGetSecretSummary)
+	api("PUT /secrets/{id}", f.handleWriteSecret)
+	api("DELETE /secrets/{id}", f.handleDeleteSecret)
+	api("PATCH /secrets/{id}/general", f.handlePatchSecret)
+	api("POST /secrets/{id}/check-out", f.handleCheckOut)
+	api("POST /secrets/{id}/extend-check-out", f.handleExtendCheckOut)
+	api("POST /secrets/{id}/check-in", f.handleCheckIn)
+	api("POST /secrets/{id}/restricted", f.handleGetSecretRestricted)
+	api("POST /secrets/{id}/restricted/fields/{slug}", f.handleGetFileRestricted)
+	api("GET /secrets/{id}/fields/{slug}", f.handleGetFile)
+	api("PUT /secrets/{id}/fields/{slug}", f.handleUploadFile)
+	api("GET /secret-templates", f.handleSearchTemplates)
+	api("GET /secret-templates/{id}", f.handleGetTemplate)
+	api("GET /folders", f.handleSearchFolders)
+	api("POST /folders", f.handleWriteFolder)
+	api("GET /folders/{id}", f.handleGetFolder)
+	api("PUT /folders/{id}", f.handleWriteFolder)
+	api("DELETE /folders/{id}", f.handleDeleteFolder)
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// providerConfig returns a provider block that connects to the fake server
+func (f *fakeSecretServer) providerConfig() string {
+	return fmt.Sprintf(`
+provider "tss" {
+  server_url = %q
+  username   = %q
+  password   = %q
+}
+`, f.URL, fakeUsername, fakePassword)
+}
+
+// addSecret stores a secret built from the template and the given values by field
+// slug, and returns its ID
+func (f *fakeSecretServer) addSecret(name string, folderID, templateID int, values map[string]string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	template := f.templates[templateID]
+	secret := &server.Secret{
+		ID:               f.newID(),
+		Name:             name,
+		FolderID:         folderID,
+		SiteID:           1,
+		SecretTemplateID: templateID,
+		Active:           true,
+	}
+	for _, field := range template.Fields {
+		secret.Fields = append(secret.Fields, f.newField(field, values[field.FieldSlugName]))
+	}
+	f.secrets[secret.ID] = secret
+	return secret.ID
+}
+
+// addFolder stores a folder below the given parent folder and returns its ID
+func (f *fakeSecretServer) addFolder(name string, parentID int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	folder := &Folder{
+		ID:                  f.newID(),
+		FolderName:          name,
+		ParentFolderID:      parentID,
+		FolderTypeID:        folderTypeID,
+		InheritPermissions:  true,
+		InheritSecretPolicy: true,
+	}
+	folder.FolderPath = f.folderPath(folder)
+	f.folders[folder.ID] = folder
+	return folder.ID
+}
+
+// secret returns a copy of the stored secret with the given ID
+func (f *fakeSecretServer) secret(id int) (server.Secret, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secrets[id]
+	if !ok {
+		return server.Secret{}, false
+	}
+	return copySecret(secret), true
+}
+
+// fieldValue returns the stored value of a field of a secret
+func (f *fakeSecretServer) fieldValue(id int, slug string) (string, bool) {
+	secret, ok := f.secret(id)
+	if !ok {
+		return "", false
+	}
+	for _, field := range secret.Fields {
+		if field.Slug == slug {
+			return field.ItemValue, true
+		}
+	}
+	return "", false
+}
+
+// setFieldValue changes a field of a stored secret, as if it was edited outside of Terraform
+func (f *fakeSecretServer) setFieldValue(id int, slug, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, field := range f.secrets[id].Fields {
+		if field.Slug == slug {
+			f.secrets[id].Fields[i].ItemValue = value
+		}
+	}
+}
+
+// enableCheckout makes a stored secret require checkout before it can be read
+func (f *fakeSecretServer) enableCheckout(id, intervalMinutes int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.secrets[id].CheckOutEnabled = true
+	f.secrets[id].CheckOutIntervalMinutes = intervalMinutes
+}
+
+// requireComment makes a stored secret require a comment before it can be viewed
+func (f *fakeSecretServer) requireComment(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.secrets[id].RequiresComment = true
+}
+
+// viewJustifications returns the justifications given to view a secret
+func (f *fakeSecretServer) viewJustifications(id int) []secretAccess {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.views[id])
+}
+
+// checkedOut reports whether a stored secret is checked out
+func (f *fakeSecretServer) checkedOut(id int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.secrets[id].CheckedOut
+}
+
+// checkoutComments returns the comments of all checkouts of a secret
+func (f *fakeSecretServer) checkoutComments(id int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return slices.Clone(f.comments[id])
+}
+
+// file returns the attachment of a field of a secret
+func (f *fakeSecretServer) file(id int, slug string) (fakeFile, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, ok := f.files[id][slug]
+	return file, ok
+}
+
+// deleteSecret removes a secret, as if it was deleted outside of Terraform
+func (f *fakeSecretServer) deleteSecret(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.secrets, id)
+	delete(f.files, id)
+}
+
+// deactivateSecret deactivates a secret, the way Secret Server deletes secrets
+func (f *fakeSecretServer) deactivateSecret(id int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.secrets[id].Active = false
+	f.deactivated[id] = true
+}
+
+// secretIDs returns the IDs of all stored secrets
+func (f *fakeSecretServer) secretIDs() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]int, 0, len(f.secrets))
+	for id := range f.secrets {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// failNext makes the next requests to the given method and path, such as
+// "GET /api/v1/secrets/100", fail with the given status codes, in order
+func (f *fakeSecretServer) failNext(request string, statusCodes ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures[request] = append(f.failures[request], statusCodes...)
+}
+
+// setRetryAfter makes injected failures include the given Retry-After header
+func (f *fakeSecretServer) setRetryAfter(value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.retryAfter = value
+}
+
+// setLatency delays every API response by the given duration
+func (f *fakeSecretServer) setLatency(latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.latency = latency
+}
+
+// maxConcurrentRequests returns the highest number of API requests that were
+// handled at the same time
+func (f *fakeSecretServer) maxConcurrentRequests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.maxInFlight
+}
+
+// revokeTokens invalidates every access token handed out so far
+func (f *fakeSecretServer) revokeTokens() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokens = map[string]bool{}
+}
+
+// tokenRequestCount returns the number of successful token requests
+func (f *fakeSecretServer) tokenRequestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.tokenRequests
+}
+
+// requestCount returns the number of API requests with the given method and path
+func (f *fakeSecretServer) requestCount(request string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count := 0
+	for _, r := range f.requests {
+		if r == request {
+			count++
+		}
+	}
+	return count
+}
+
+func (f *fakeSecretServer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "password" ||
+		r.PostForm.Get("username") != fakeUsername || r.PostForm.Get("password") != fakePassword {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	f.mu.Lock()
+	f.tokenRequests++
+	token := fmt.Sprintf("token-%d", f.tokenRequests)
+	f.tokens[token] = true
+	f.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   1200,
+	})
+}
+
+// authenticated records the request, answers with an injected failure if there is
+// one and rejects requests without a valid access token
+func (f *fakeSecretServer) authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request := r.Method + " " + r.URL.Path
+
+		f.mu.Lock()
+		f.requests = append(f.requests, request)
+		var failure int
+		if statusCodes := f.failures[request]; len(statusCodes) > 0 {
+			failure, f.failures[request] = statusCodes[0], statusCodes[1:]
+		}
+		valid := f.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		retryAfter := f.retryAfter
+		latency := f.latency
+		f.inFlight++
+		f.maxInFlight = max(f.maxInFlight, f.inFlight)
+		f.mu.Unlock()
+
+		defer func() {
+			f.mu.Lock()
+			f.inFlight--
+			f.mu.Unlock()
+		}()
+		time.Sleep(latency)
+
+		switch {
+		case failure != 0:
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			writeError(w, failure, http.StatusText(failure))
+		case !valid:
+			writeError(w, http.StatusUnauthorized, "Authentication failed.")
+		default:
+			handler(w, r)
+		}
+	}
+}
+
+func (f *fakeSecretServer) handleSearchSecrets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	searchText := strings.ToLower(query.Get("paging.filter.searchText"))
+	searchField := query.Get("paging.filter.searchField")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Without a search field, the name and the extended fields are searched
+	matches := func(secret *server.Secret) bool {
+		if searchField == "" && strings.Contains(strings.ToLower(secret.Name), searchText) {
+			return true
+		}
+		for _, field := range secret.Fields {
+			if searchField != "" {
+				if field.Slug == searchField && strings.ToLower(field.ItemValue) == searchText {
+					return true
+				}
+				continue
+			}
+			for _, extended := range query["paging.filter.extendedFields"] {
+				if strings.EqualFold(field.FieldName, extended) && strings.Contains(strings.ToLower(field.ItemValue), searchText) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	folderID, hasFolder := query.Get("paging.filter.folderId"), query.Has("paging.filter.folderId")
+	skip, _ := strconv.Atoi(query.Get("paging.skip"))
+	take, err := strconv.Atoi(query.Get("paging.take"))
+	if err != nil {
+		take = 30
+	}
+
+	result := server.SearchResult{SearchText: searchText, Records: []server.Secret{}}
+	for _, id := range slices.Sorted(maps.Keys(f.secrets)) {
+		secret := f.secrets[id]
+		if hasFolder && strconv.Itoa(secret.FolderID) != folderID {
+			continue
+		}
+		if !matches(secret) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if len(result.Records) == take {
+			break
+		}
+		result.Records = append(result.Records, server.Secret{
+			ID:               secret.ID,
+			Name:             secret.Name,
+			FolderID:         secret.FolderID,
+			SecretTemplateID: secret.SecretTemplateID,
+			Active:           secret.Active,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (f *fakeSecretServer) handleGetSecret(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.viewableSecret(w, r, secretAccess{})
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, f.secretResponse(secret))
+}
+
+// handleGetSecretSummary returns the summary of a secret, which needs no checkout
+// or comment and includes deactivated secrets
+func (f *fakeSecretServer) handleGetSecretSummary(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":               secret.ID,
+		"name":             secret.Name,
+		"folderId":         secret.FolderID,
+		"secretTemplateId": secret.SecretTemplateID,
+		"active":           secret.Active,
+	})
+}
+
+// handleGetSecretRestricted returns a secret like handleGetSecret, with the comment
+// and ticket number in the request body
+func (f *fakeSecretServer) handleGetSecretRestricted(w http.ResponseWriter, r *http.Request) {
+	var access secretAccess
+	if err := json.NewDecoder(r.Body).Decode(&access); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.viewableSecret(w, r, access)
+	if !ok {
+		return
+	}
+	f.views[secret.ID] = append(f.views[secret.ID], access)
+	writeJSON(w, http.StatusOK, f.secretResponse(secret))
+}
+
+// viewableSecret returns the secret in the request path if the justification meets
+// its requirements, and writes an error response otherwise
+func (f *fakeSecretServer) viewableSecret(w http.ResponseWriter, r *http.Request, access secretAccess) (*server.Secret, bool) {
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return nil, false
+	}
+	if f.deactivated[secret.ID] {
+		writeError(w, http.StatusBadRequest, "Access Denied")
+		return nil, false
+	}
+	if secret.CheckOutEnabled && !secret.CheckedOut {
+		writeError(w, http.StatusBadRequest, "The secret requires check out.")
+		return nil, false
+	}
+	if secret.RequiresComment && access.Comment == "" {
+		writeError(w, http.StatusBadRequest, "The secret requires a comment.")
+		return nil, false
+	}
+	return secret, true
+}
+
+// handleWriteSecret creates a secret on POST and updates one on PUT. Only the fields
+// in the request are changed; file fields are ignored, as they are uploaded separately.
+func (f *fakeSecretServer) handleWriteSecret(w http.ResponseWriter, r *http.Request) {
+	var input server.Secret
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var secret *server.Secret
+	if r.Method == http.MethodPost {
+		template, ok := f.templates[input.SecretTemplateID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Secret template %d not found.", input.SecretTemplateID))
+			return
+		}
+		secret = &server.Secret{ID: f.newID(), SecretTemplateID: template.ID}
+		for _, field := range template.Fields {
+			secret.Fields = append(secret.Fields, f.newField(field, ""))
+		}
+	} else {
+		var ok bool
+		if secret, ok = f.secretFromPath(w, r); !ok {
+			return
+		}
+		if input.SshKeyArgs != nil && (input.SshKeyArgs.GenerateSshKeys || input.SshKeyArgs.GeneratePassphrase) {
+			writeError(w, http.StatusBadRequest, "SSH keys can only be generated when a secret is created.")
+			return
+		}
+	}
+
+	if input.Name == "" {
+		writeError(w, http.StatusBadRequest, "The secret name is required.")
+		return
+	}
+	secret.Name = input.Name
+	secret.FolderID = input.FolderID
+	secret.SiteID = input.SiteID
+	secret.SecretPolicyID = input.SecretPolicyID
+	secret.Active = input.Active
+	secret.CheckOutEnabled = input.CheckOutEnabled
+	secret.CheckOutIntervalMinutes = input.CheckOutIntervalMinutes
+	secret.RequiresComment = input.RequiresComment
+
+	for _, item := range input.Fields {
+		i := fieldIndex(secret, item.Slug, item.FieldID)
+		if i < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("The field '%s' is not on the secret template.", item.Slug))
+			return
+		}
+		if !secret.Fields[i].IsFile {
+			secret.Fields[i].ItemValue = item.ItemValue
+		}
+	}
+
+	if args := input.SshKeyArgs; args != nil {
+		if args.GenerateSshKeys {
+			f.attach(secret.ID, "private-key", "private_key.pem", fmt.Sprintf("generated private key %d", secret.ID))
+			f.attach(secret.ID, "public-key", "public_key.pub", fmt.Sprintf("generated public key %d", secret.ID))
+		}
+		if args.GeneratePassphrase {
+			if i := fieldIndex(secret, "private-key-passphrase", 0); i >= 0 {
+				secret.Fields[i].ItemValue = fmt.Sprintf("generated passphrase %d", secret.ID)
+			}
+		}
+	}
+
+	f.secrets[secret.ID] = secret
+	writeJSON(w, http.StatusOK, f.secretResponse(secret))
+}
+
+func (f *fakeSecretServer) handleDeleteSecret(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	delete(f.secrets, secret.ID)
+	delete(f.files, secret.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": secret.ID, "objectType": "Secret"})
+}
+
+// handlePatchSecret handles the general field updates the SDK uses to remove attachments
+func (f *fakeSecretServer) handlePatchSecret(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Data struct {
+			SecretFields []struct {
+				Slug  string
+				Dirty bool
+				Value *string
+			}
+		}
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	for _, change := range input.Data.SecretFields {
+		i := fieldIndex(secret, change.Slug, 0)
+		if i < 0 || !change.Dirty {
+			continue
+		}
+		switch {
+		case secret.Fields[i].IsFile && change.Value == nil:
+			delete(f.files[secret.ID], change.Slug)
+		case change.Value != nil:
+			secret.Fields[i].ItemValue = *change.Value
+		}
+	}
+	writeJSON(w, http.StatusOK, f.secretResponse(secret))
+}
+
+// handleCheckOut checks out a secret and records the comment
+func (f *fakeSecretServer) handleCheckOut(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Comment string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !secret.CheckOutEnabled {
+		writeError(w, http.StatusBadRequest, "Check out is not enabled for the secret.")
+		return
+	}
+	secret.CheckedOut = true
+	f.comments[secret.ID] = append(f.comments[secret.ID], input.Comment)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": secret.ID, "checkedOut": true})
+}
+
+// handleExtendCheckOut extends the checkout of a secret, which fails if it is not
+// checked out
+func (f *fakeSecretServer) handleExtendCheckOut(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !secret.CheckedOut {
+		writeError(w, http.StatusBadRequest, "The secret is not checked out.")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": secret.ID, "checkedOut": true})
+}
+
+// handleCheckIn checks in a secret, which fails if it is not checked out
+func (f *fakeSecretServer) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	if !secret.CheckedOut {
+		writeError(w, http.StatusBadRequest, "The secret is not checked out.")
+		return
+	}
+	secret.CheckedOut = false
+	writeJSON(w, http.StatusOK, f.secretResponse(secret))
+}
+
+func (f *fakeSecretServer) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeFile(w, r, secretAccess{})
+}
+
+func (f *fakeSecretServer) handleGetFileRestricted(w http.ResponseWriter, r *http.Request) {
+	var access secretAccess
+	if err := json.NewDecoder(r.Body).Decode(&access); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.writeFile(w, r, access)
+}
+
+// writeFile writes the attachment of the field in the request path
+func (f *fakeSecretServer) writeFile(w http.ResponseWriter, r *http.Request, access secretAccess) {
+	secret, ok := f.viewableSecret(w, r, access)
+	if !ok {
+		return
+	}
+	file, ok := f.files[secret.ID][r.PathValue("slug")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "File not found.")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+	_, _ = w.Write(file.Content)
+}
+
+func (f *fakeSecretServer) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	upload, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer upload.Close()
+	content, err := io.ReadAll(upload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secret, ok := f.secretFromPath(w, r)
+	if !ok {
+		return
+	}
+	slug := r.PathValue("slug")
+	if i := fieldIndex(secret, slug, 0); i < 0 || !secret.Fields[i].IsFile {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("The field '%s' is not a file field.", slug))
+		return
+	}
+	f.attach(secret.ID, slug, header.Filename, string(content))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *fakeSecretServer) handleSearchTemplates(w http.ResponseWriter, r *http.Request) {
+	searchText := strings.ToLower(r.URL.Query().Get("filter.searchText"))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := secretTemplateSearchResult{Records: []secretTemplateSummary{}}
+	for _, id := range slices.Sorted(maps.Keys(f.templates)) {
+		template := f.templates[id]
+		if strings.Contains(strings.ToLower(template.Name), searchText) {
+			result.Records = append(result.Records, secretTemplateSummary{ID: template.ID, Name: template.Name, Active: true})
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (f *fakeSecretServer) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	template, ok := f.templates[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Secret template not found.")
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+func (f *fakeSecretServer) handleSearchFolders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	searchText := strings.ToLower(query.Get("filter.searchText"))
+	skip, _ := strconv.Atoi(query.Get("skip"))
+	take, err := strconv.Atoi(query.Get("take"))
+	if err != nil {
+		take = 30
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := folderSearchResult{Records: []Folder{}}
+	for _, id := range slices.Sorted(maps.Keys(f.folders)) {
+		folder := f.folders[id]
+		if !strings.Contains(strings.ToLower(folder.FolderName), searchText) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if len(result.Records) == take {
+			break
+		}
+		result.Records = append(result.Records, *folder)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (f *fakeSecretServer) handleGetFolder(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	folder, ok := f.folderFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, folder)
+}
+
+// handleWriteFolder creates a folder on POST and updates one on PUT
+func (f *fakeSecretServer) handleWriteFolder(w http.ResponseWriter, r *http.Request) {
+	var input Folder
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	folder := &Folder{ID: f.newID()}
+	if r.Method == http.MethodPut {
+		var ok bool
+		if folder, ok = f.folderFromPath(w, r); !ok {
+			return
+		}
+	}
+	if _, ok := f.folders[input.ParentFolderID]; !ok && input.ParentFolderID != rootFolderID {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Parent folder %d not found.", input.ParentFolderID))
+		return
+	}
+
+	folder.FolderName = input.FolderName
+	folder.ParentFolderID = input.ParentFolderID
+	folder.FolderTypeID = input.FolderTypeID
+	folder.InheritPermissions = input.InheritPermissions
+	folder.InheritSecretPolicy = input.InheritSecretPolicy
+	folder.SecretPolicyID = input.SecretPolicyID
+	folder.FolderPath = f.folderPath(folder)
+	f.folders[folder.ID] = folder
+	writeJSON(w, http.StatusOK, folder)
+}
+
+func (f *fakeSecretServer) handleDeleteFolder(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	folder, ok := f.folderFromPath(w, r)
+	if !ok {
+		return
+	}
+	delete(f.folders, folder.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": folder.ID, "objectType": "Folder"})
+}
+
+// secretFromPath returns the secret named by the id path value, or writes a 404
+func (f *fakeSecretServer) secretFromPath(w http.ResponseWriter, r *http.Request) (*server.Secret, bool) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	secret, ok := f.secrets[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Secret not found.")
+	}
+	return secret, ok
+}
+
+// folderFromPath returns the folder named by the id path value, or writes a 404
+func (f *fakeSecretServer) folderFromPath(w http.ResponseWriter, r *http.Request) (*Folder, bool) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	folder, ok := f.folders[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Folder not found.")
+	}
+	return folder, ok
+}
+
+// secretResponse returns the secret as the API returns it, with the attachment
+// details in file fields instead of their content
+func (f *fakeSecretServer) secretResponse(secret *server.Secret) server.Secret {
+	response := copySecret(secret)
+	for i, field := range response.Fields {
+		if !field.IsFile {
+			continue
+		}
+		response.Fields[i].ItemValue = ""
+		response.Fields[i].FileAttachmentID = 0
+		response.Fields[i].Filename = ""
+		if file, ok := f.files[secret.ID][field.Slug]; ok {
+			response.Fields[i].ItemValue = fileNotForDisplay
+			response.Fields[i].FileAttachmentID = file.ID
+			response.Fields[i].Filename = file.Name
+		}
+	}
+	return response
+}
+
+// attach stores a file for a field of a secret
+func (f *fakeSecretServer) attach(secretID int, slug, name, content string) {
+	if f.files[secretID] == nil {
+		f.files[secretID] = map[string]fakeFile{}
+	}
+	f.files[secretID][slug] = fakeFile{ID: f.newID(), Name: name, Content: []byte(content)}
+}
+
+// newField returns an item for the template field with the given value
+func (f *fakeSecretServer) newField(field server.SecretTemplateField, value string) server.SecretField {
+	return server.SecretField{
+		ItemID:           f.newID(),
+		FieldID:          field.SecretTemplateFieldID,
+		FieldName:        field.Name,
+		Slug:             field.FieldSlugName,
+		FieldDescription: field.Description,
+		ItemValue:        value,
+		IsFile:           field.IsFile,
+		IsNotes:          field.IsNotes,
+		IsPassword:       field.IsPassword,
+	}
+}
+
+// newID returns an ID not used by any other object
+func (f *fakeSecretServer) newID() int {
+	f.nextID++
+	return f.nextID
+}
+
+// folderPath returns the full path of a folder from the names of its ancestors
+func (f *fakeSecretServer) folderPath(folder *Folder) string {
+	path := folderPathSeparator + folder.FolderName
+	if parent, ok := f.folders[folder.ParentFolderID]; ok {
+		path = f.folderPath(parent) + path
+	}
+	return path
+}
+
+// fieldIndex returns the index of the field with the given slug or field ID, or -1
+func fieldIndex(secret *server.Secret, slug string, fieldID int) int {
+	for i, field := range secret.Fields {
+		if (slug != "" && field.Slug == slug) || (slug == "" && field.FieldID == fieldID) {
+			return i
+		}
+	}
+	return -1
+}
+
+// copySecret returns a copy of the secret that shares no fields with it
+func copySecret(secret *server.Secret) server.Secret {
+	result := *secret
+	result.Fields = append([]server.SecretField(nil), secret.Fields...)
+	result.SshKeyArgs = nil
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]string{"message": message})
+}
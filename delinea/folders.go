@@ -0,0 +1,117 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// folderPathSeparator separates the folder names in a Secret Server folder path
+const folderPathSeparator = `\`
+
+// rootFolderID is the parent folder ID of top-level folders
+const rootFolderID = -1
+
+// folderTypeID is the folder type of regular folders
+const folderTypeID = 1
+
+// Folder is a folder in Secret Server
+type Folder struct {
+	ID                  int    `json:"id"`
+	FolderName          string `json:"folderName"`
+	FolderPath          string `json:"folderPath"`
+	ParentFolderID      int    `json:"parentFolderId"`
+	FolderTypeID        int    `json:"folderTypeId"`
+	SecretPolicyID      int    `json:"secretPolicyId,omitempty"`
+	InheritSecretPolicy bool   `json:"inheritSecretPolicy"`
+	InheritPermissions  bool   `json:"inheritPermissions"`
+}
+
+// folderSearchResult is a page of folders returned by the folder search
+type folderSearchResult struct {
+	Records []Folder `json:"records"`
+}
+
+// Folder gets the folder with the given ID
+func (c *Client) Folder(ctx context.Context, id int) (*Folder, error) {
+	folder := new(Folder)
+	if err := c.do(ctx, "GET", fmt.Sprintf("folders/%d", id), nil, nil, folder); err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// CreateFolder creates the given folder and returns it as stored by the server
+func (c *Client) CreateFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	folder.FolderTypeID = folderTypeID
+
+	created := new(Folder)
+	if err := c.do(ctx, "POST", "folders", nil, folder, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateFolder updates the given folder and returns it as stored by the server
+func (c *Client) UpdateFolder(ctx context.Context, folder Folder) (*Folder, error) {
+	folder.FolderTypeID = folderTypeID
+
+	updated := new(Folder)
+	if err := c.do(ctx, "PUT", fmt.Sprintf("folders/%d", folder.ID), nil, folder, updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteFolder deletes the folder with the given ID
+func (c *Client) DeleteFolder(ctx context.Context, id int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("folders/%d", id), nil, nil, nil)
+}
+
+// FolderByPath gets the folder at the given path, such as `\Parent\Child` or
+// `Parent/Child`. Both separators are accepted and the comparison ignores case.
+func (c *Client) FolderByPath(ctx context.Context, folderPath string) (*Folder, error) {
+	wanted := normalizeFolderPath(folderPath)
+	if wanted == folderPathSeparator {
+		return nil, fmt.Errorf("the folder path must name at least one folder")
+	}
+
+	// Search by the last folder name, then match the full path
+	segments := strings.Split(wanted, folderPathSeparator)
+	query := url.Values{
+		"filter.searchText": {segments[len(segments)-1]},
+		"take":              {strconv.Itoa(secretSearchPageSize)},
+	}
+
+	for skip := 0; ; skip += secretSearchPageSize {
+		query.Set("skip", strconv.Itoa(skip))
+
+		var result folderSearchResult
+		if err := c.do(ctx, "GET", "folders", query, nil, &result); err != nil {
+			return nil, fmt.Errorf("failed to search folders: %w", err)
+		}
+
+		for _, folder := range result.Records {
+			if strings.EqualFold(normalizeFolderPath(folder.FolderPath), wanted) {
+				return &folder, nil
+			}
+		}
+
+		// A short page is the last one
+		if len(result.Records) < secretSearchPageSize {
+			break
+		}
+	}
+	return nil, fmt.Errorf("no folder found at path '%s'", folderPath)
+}
+
+// normalizeFolderPath returns the path with backslash separators, a leading
+// separator and no empty segments
+func normalizeFolderPath(folderPath string) string {
+	segments := strings.FieldsFunc(folderPath, func(r rune) bool {
+		return r == '/' || r == '\\'
+	})
+	return folderPathSeparator + strings.Join(segments, folderPathSeparator)
+}
@@ -0,0 +1,104 @@
+package delinea
+
+import (
+	"context"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Log subsystems of the provider, filtered with TF_LOG_PROVIDER_TSS_<SUBSYSTEM>
+const (
+	logClient         = "client"
+	logResourceSecret = "resource_secret"
+	logEphemeral      = "ephemeral"
+)
+
+// logSubsystems are all log subsystems of the provider
+var logSubsystems = []string{logClient, logResourceSecret, logEphemeral}
+
+// sensitiveLogKeys are log field keys whose values are always masked
+var sensitiveLogKeys = []string{
+	"password", "token", "access_token", "client_secret", "passphrase",
+	"value", "values", "itemvalue", "itemvalue_wo", "sensitive_values", "file_content_base64",
+}
+
+// minMaskedValueLength is the length below which values of fields that are not
+// sensitive are not masked, as masking short values such as "1" would garble every
+// log line. Passwords, private keys and passphrases are masked whatever their length.
+const minMaskedValueLength = 4
+
+// withLogging sets up the client subsystem and the given subsystems in a request
+// context, masking the sensitive field keys in all of them. It must be called once
+// at the start of a request: creating a subsystem again drops its masks.
+func withLogging(ctx context.Context, subsystems ...string) context.Context {
+	ctx = tflog.MaskFieldValuesWithFieldKeys(ctx, sensitiveLogKeys...)
+	for _, subsystem := range append([]string{logClient}, subsystems...) {
+		ctx = tflog.NewSubsystem(ctx, subsystem)
+		ctx = tflog.SubsystemMaskFieldValuesWithFieldKeys(ctx, subsystem, sensitiveLogKeys...)
+	}
+	return ctx
+}
+
+// maskLogStrings masks the given values in the messages and fields of all log output
+func maskLogStrings(ctx context.Context, values ...string) context.Context {
+	if len(values) == 0 {
+		return ctx
+	}
+	ctx = tflog.MaskLogStrings(ctx, values...)
+	for _, subsystem := range logSubsystems {
+		ctx = tflog.SubsystemMaskLogStrings(ctx, subsystem, values...)
+	}
+	return ctx
+}
+
+// nonEmpty returns the values that are not empty
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// maskSecretValues masks the field values of the given secrets in all log output, so
+// that they cannot be logged even at TRACE level
+func maskSecretValues(ctx context.Context, secrets ...*server.Secret) context.Context {
+	var values []string
+	for _, secret := range secrets {
+		if secret == nil {
+			continue
+		}
+		for _, field := range secret.Fields {
+			if field.ItemValue == "" {
+				continue
+			}
+			if isSensitiveField(field.IsPassword, field.FieldName, field.Slug) || len(field.ItemValue) >= minMaskedValueLength {
+				values = append(values, field.ItemValue)
+			}
+		}
+	}
+	return maskLogStrings(ctx, values...)
+}
+
+// maskStateValues masks the field values and sensitive values of a secret resource
+// state in all log output
+func maskStateValues(ctx context.Context, state *SecretResourceState) context.Context {
+	var values []string
+	for _, field := range state.Fields {
+		value := field.ItemValue.ValueString()
+		sensitive := isSensitiveField(field.IsPassword.ValueBool(), field.FieldName.ValueString(), field.Slug.ValueString())
+		if value != "" && (sensitive || len(value) >= minMaskedValueLength) {
+			values = append(values, value)
+		}
+	}
+	for _, value := range state.SensitiveValues.Elements() {
+		if s, ok := value.(types.String); ok && s.ValueString() != "" {
+			values = append(values, s.ValueString())
+		}
+	}
+	return maskLogStrings(ctx, values...)
+}
@@ -0,0 +1,56 @@
+package delinea
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestMaskSecretValues(t *testing.T) {
+	var output bytes.Buffer
+	ctx := withLogging(tflogtest.RootLogger(context.Background(), &output))
+	ctx = maskSecretValues(ctx, &server.Secret{Fields: []server.SecretField{
+		{FieldName: "Password", Slug: "password", ItemValue: "pw1", IsPassword: true},
+		{FieldName: "Private Key Passphrase", Slug: "private-key-passphrase", ItemValue: "pp"},
+		{FieldName: "Username", Slug: "username", ItemValue: "administrator"},
+		{FieldName: "Port", Slug: "port", ItemValue: "22"},
+	}})
+
+	tflog.Debug(ctx, "values pw1 pp administrator 22")
+
+	logged := output.String()
+	for _, value := range []string{"pw1", " pp ", "administrator"} {
+		if strings.Contains(logged, value) {
+			t.Errorf("log output contains %q: %s", value, logged)
+		}
+	}
+	if !strings.Contains(logged, " 22") {
+		t.Errorf("log output does not contain the short value of a field that is not sensitive: %s", logged)
+	}
+}
+
+func TestMaskStateValues(t *testing.T) {
+	var output bytes.Buffer
+	ctx := withLogging(tflogtest.RootLogger(context.Background(), &output), logResourceSecret)
+	ctx = maskStateValues(ctx, &SecretResourceState{
+		Fields: []SecretField{
+			{FieldName: types.StringValue("Password"), Slug: types.StringValue("password"), ItemValue: types.StringValue("abc"), IsPassword: types.BoolValue(true)},
+			{FieldName: types.StringValue("Private Key"), Slug: types.StringValue("private-key"), ItemValue: types.StringValue("k"), IsPassword: types.BoolValue(false)},
+		},
+		SensitiveValues: types.MapValueMust(types.StringType, map[string]attr.Value{"passphrase": types.StringValue("xy")}),
+	})
+
+	tflog.SubsystemDebug(ctx, logResourceSecret, "values abc k xy")
+
+	logged := output.String()
+	if strings.Contains(logged, "abc") || strings.Contains(logged, " k ") || strings.Contains(logged, "xy") {
+		t.Errorf("log output contains a sensitive value: %s", logged)
+	}
+}
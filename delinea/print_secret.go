@@ -72,8 +72,8 @@ func (r *PrintSecretResource) Create(ctx context.Context, req resource.CreateReq
 	}
 
 	//For the DEBUG environment, uncomment this line to unit test whether the secret value is being fetched successfully.
-	//log.Printf("[DEBUG] Received secret: %s", data.Secret.ValueString())
-	//log.Printf("[DEBUG]: Received secrets: %+v\n", data.Secrets)
+	//tflog.Debug(ctx, "Received secret", map[string]interface{}{"secret": data.Secret.ValueString()})
+	//tflog.Debug(ctx, "Received secrets", map[string]interface{}{"secrets": data.Secrets})
 
 	// Set state
 	diags = resp.State.Set(ctx, data)
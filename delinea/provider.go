@@ -2,17 +2,22 @@ package delinea
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/DelineaXPM/tss-sdk-go/v2/server"
-	"github.com/hashicorp/terraform-plugin-framework-validators/providervalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // Define the provider structure
@@ -25,8 +30,22 @@ type TSSProviderModel struct {
 	Password  types.String `tfsdk:"password"`
 	Token     types.String `tfsdk:"token"`
 	Domain    types.String `tfsdk:"domain"`
+
+	MaxConcurrentRequests types.Int64  `tfsdk:"max_concurrent_requests"`
+	MaxRetries            types.Int64  `tfsdk:"max_retries"`
+	Backoff               types.String `tfsdk:"backoff"`
+	Jitter                types.Bool   `tfsdk:"jitter"`
 }
 
+// Environment variables used for provider settings that are not set in the configuration
+const (
+	envServerURL = "TSS_SERVER_URL"
+	envUsername  = "TSS_USERNAME"
+	envPassword  = "TSS_PASSWORD"
+	envToken     = "TSS_TOKEN"
+	envDomain    = "TSS_DOMAIN"
+)
+
 // Ensure the provider implements the ProviderWithEphemeralResources interface
 var _ provider.ProviderWithEphemeralResources = (*TSSProvider)(nil)
 
@@ -40,41 +59,60 @@ func (p *TSSProvider) Schema(ctx context.Context, req provider.SchemaRequest, re
 	resp.Schema = schema.Schema{
 		Attributes: map[string]schema.Attribute{
 			"server_url": schema.StringAttribute{
-				Required:    true,
-				Description: "The Secret Server base URL e.g. https://localhost/SecretServer",
+				Optional:    true,
+				Description: "The Secret Server base URL e.g. https://localhost/SecretServer. Can also be set with the TSS_SERVER_URL environment variable.",
 			},
 			"username": schema.StringAttribute{
 				Optional:    true,
-				Description: "The username of the Secret Server User to connect as",
+				Description: "The username of the Secret Server User to connect as. Can also be set with the TSS_USERNAME environment variable.",
 			},
 			"password": schema.StringAttribute{
 				Optional:    true,
 				Sensitive:   true,
-				Description: "The password of the Secret Server User",
+				Description: "The password of the Secret Server User. Can also be set with the TSS_PASSWORD environment variable.",
 			},
 			"token": schema.StringAttribute{
 				Optional:    true,
 				Sensitive:   true,
-				Description: "A token to authenticate the Secret Server User",
+				Description: "A token to authenticate the Secret Server User. Can also be set with the TSS_TOKEN environment variable.",
 			},
 			"domain": schema.StringAttribute{
 				Optional:    true,
-				Description: "Domain of the Secret Server user",
+				Description: "Domain of the Secret Server user. Can also be set with the TSS_DOMAIN environment variable.",
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("The maximum number of requests sent to Secret Server at the same time, shared by all resources and data sources. Defaults to %d.", defaultMaxConcurrentRequests),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(1),
+				},
+			},
+			"max_retries": schema.Int64Attribute{
+				Optional:    true,
+				Description: fmt.Sprintf("How often a request is retried when Secret Server answers with 429 Too Many Requests or 503 Service Unavailable. Set to 0 to disable retries. Defaults to %d.", defaultMaxRetries),
+				Validators: []validator.Int64{
+					int64validator.AtLeast(0),
+				},
+			},
+			"backoff": schema.StringAttribute{
+				Optional:    true,
+				Description: fmt.Sprintf("The delay before the first retry as a duration such as \"500ms\" or \"2s\". It doubles with every further retry up to %s, and a longer Retry-After from the server takes precedence. Defaults to %q.", maxBackoff, defaultBackoff),
+				Validators: []validator.String{
+					durationValidator{},
+				},
+			},
+			"jitter": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to randomize the delays between retries so that concurrent requests do not retry at the same time. Defaults to true.",
 			},
 		},
 	}
 }
 
+// ConfigValidators validates the credentials after merging the configuration with the environment
 func (p *TSSProvider) ConfigValidators(ctx context.Context) []provider.ConfigValidator {
-	return []provider.ConfigValidator {
-			providervalidator.RequiredTogether(
-				path.MatchRoot("username"),
-				path.MatchRoot("password"),
-			),
-			providervalidator.ExactlyOneOf(
-				path.MatchRoot("username"),
-				path.MatchRoot("token"),
-			),
+	return []provider.ConfigValidator{
+		credentialsConfigValidator{},
 	}
 }
 
@@ -82,24 +120,53 @@ func (p *TSSProvider) ConfigValidators(ctx context.Context) []provider.ConfigVal
 func (p *TSSProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
 	var config TSSProviderModel
 
-	// Log the start of the Configure method
-	log.Printf("Starting Configure method")
+	ctx = withLogging(ctx)
+	tflog.Debug(ctx, "Configuring the provider")
 
 	// Read configuration values into the config struct
 	diags := req.Config.Get(ctx, &config)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		resp.Diagnostics.AddError("Configuration Error", "Failed to read provider configuration")
-		log.Printf("Failed to read provider configuration", map[string]interface{}{
-			"diagnostics": resp.Diagnostics,
-		})
 		return
 	}
 
-	// Log the configuration values
-	log.Printf("Provider configuration values retrieved", map[string]interface{}{
+	// Fill in anything not set in the configuration from the environment
+	config = config.withEnvironment()
+
+	// Values that depend on other resources are not known until apply, and the
+	// provider cannot connect without them
+	for attribute, value := range map[string]types.String{
+		"server_url": config.ServerURL,
+		"username":   config.Username,
+		"password":   config.Password,
+		"token":      config.Token,
+		"domain":     config.Domain,
+	} {
+		if value.IsUnknown() {
+			resp.Diagnostics.AddAttributeError(
+				path.Root(attribute),
+				"Unknown Provider Setting",
+				"The provider cannot create the Secret Server client because the value of '"+attribute+"' is unknown. "+
+					"Set the value statically in the configuration or use the corresponding TSS_* environment variable.",
+			)
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(config.validateCredentials()...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Never log the credentials themselves
+	ctx = maskLogStrings(ctx, nonEmpty(config.Password.ValueString(), config.Token.ValueString())...)
+	tflog.Debug(ctx, "Provider configuration values retrieved", map[string]interface{}{
 		"server_url": config.ServerURL.ValueString(),
 		"username":   config.Username.ValueString(),
+		"domain":     config.Domain.ValueString(),
 	})
 
 	// Create the server configuration
@@ -113,15 +180,153 @@ func (p *TSSProvider) Configure(ctx context.Context, req provider.ConfigureReque
 		},
 	}
 
-	// Pass the server configuration to resources and data sources
-	if serverConfig == nil {
-		log.Printf("Server configuration is nil")
-		resp.Diagnostics.AddError("Configuration Error", "Server configuration is nil")
+	// Create the client shared by all resources, data sources and ephemeral resources.
+	// It authenticates on first use and keeps the access token for later calls.
+	options := ClientOptions{
+		MaxConcurrentRequests: int(config.MaxConcurrentRequests.ValueInt64()),
+		MaxRetries:            defaultMaxRetries,
+		Backoff:               defaultBackoff,
+		Jitter:                true,
+	}
+	if !config.MaxRetries.IsNull() {
+		options.MaxRetries = int(config.MaxRetries.ValueInt64())
+	}
+	if !config.Backoff.IsNull() {
+		// Already checked by the validator
+		options.Backoff, _ = time.ParseDuration(config.Backoff.ValueString())
+	}
+	if !config.Jitter.IsNull() {
+		options.Jitter = config.Jitter.ValueBool()
+	}
+
+	client, err := NewClient(*serverConfig, options)
+	if err != nil {
+		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
+		return
+	}
+
+	// Pass the client to resources and data sources
+	resp.DataSourceData = client
+	resp.ResourceData = client
+	resp.EphemeralResourceData = client
+}
+
+// withEnvironment returns a copy of the model where every unset value is taken from its
+// environment variable
+func (m TSSProviderModel) withEnvironment() TSSProviderModel {
+	m.ServerURL = stringFromEnv(m.ServerURL, envServerURL)
+	m.Username = stringFromEnv(m.Username, envUsername)
+	m.Password = stringFromEnv(m.Password, envPassword)
+	m.Token = stringFromEnv(m.Token, envToken)
+	m.Domain = stringFromEnv(m.Domain, envDomain)
+	return m
+}
+
+// validateCredentials checks that the merged settings name a server and exactly one way
+// of authenticating. Unknown values are skipped so that validation can run before apply.
+func (m TSSProviderModel) validateCredentials() diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	isSet := func(value types.String) bool {
+		return !value.IsNull() && !value.IsUnknown() && value.ValueString() != ""
+	}
+
+	if !m.ServerURL.IsUnknown() && !isSet(m.ServerURL) {
+		diags.AddAttributeError(
+			path.Root("server_url"),
+			"Missing Server URL",
+			"The Secret Server URL must be set with 'server_url' or the "+envServerURL+" environment variable.",
+		)
+	}
+
+	if m.Username.IsUnknown() || m.Password.IsUnknown() || m.Token.IsUnknown() {
+		return diags
+	}
+
+	hasUsername, hasPassword, hasToken := isSet(m.Username), isSet(m.Password), isSet(m.Token)
+
+	switch {
+	case hasUsername && hasToken:
+		diags.AddAttributeError(
+			path.Root("token"),
+			"Conflicting Credentials",
+			"Only one of 'username' ("+envUsername+") and 'token' ("+envToken+") can be set.",
+		)
+	case !hasUsername && !hasToken:
+		diags.AddAttributeError(
+			path.Root("username"),
+			"Missing Credentials",
+			"Either 'username' and 'password' ("+envUsername+" and "+envPassword+") or 'token' ("+envToken+") must be set.",
+		)
+	case hasUsername != hasPassword:
+		diags.AddAttributeError(
+			path.Root("password"),
+			"Incomplete Credentials",
+			"'username' ("+envUsername+") and 'password' ("+envPassword+") must be set together.",
+		)
+	}
+
+	return diags
+}
+
+// stringFromEnv returns the value of envVar when the configured value is null
+func stringFromEnv(value types.String, envVar string) types.String {
+	if !value.IsNull() {
+		return value
+	}
+	if envValue := os.Getenv(envVar); envValue != "" {
+		return types.StringValue(envValue)
+	}
+	return value
+}
+
+// credentialsConfigValidator validates the provider credentials after they are merged
+// with the TSS_* environment variables
+type credentialsConfigValidator struct{}
+
+func (v credentialsConfigValidator) Description(ctx context.Context) string {
+	return "Validates that the server URL and exactly one of username/password or token are set, either in the configuration or in the environment."
+}
+
+func (v credentialsConfigValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v credentialsConfigValidator) ValidateProvider(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var config TSSProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
-	resp.DataSourceData = serverConfig
-	resp.ResourceData = serverConfig
-	resp.EphemeralResourceData = serverConfig
+
+	resp.Diagnostics.Append(config.withEnvironment().validateCredentials()...)
+}
+
+// durationValidator checks that a string is a positive Go duration such as "1s" or "1m30s"
+type durationValidator struct{}
+
+func (v durationValidator) Description(ctx context.Context) string {
+	return "value must be a positive duration such as \"500ms\", \"2s\" or \"1m\""
+}
+
+func (v durationValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v durationValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+
+	duration, err := time.ParseDuration(req.ConfigValue.ValueString())
+	if err != nil || duration <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			req.Path,
+			"Invalid Duration",
+			fmt.Sprintf("%q is not valid: %s.", req.ConfigValue.ValueString(), v.Description(ctx)),
+		)
+	}
 }
 
 // DataSources returns the data sources supported by the provider
@@ -129,6 +334,8 @@ func (p *TSSProvider) DataSources(ctx context.Context) []func() datasource.DataS
 	return []func() datasource.DataSource{
 		func() datasource.DataSource { return &TSSSecretDataSource{} },
 		func() datasource.DataSource { return &TSSSecretsDataSource{} },
+		func() datasource.DataSource { return &TSSFolderDataSource{} },
+		func() datasource.DataSource { return &TSSSecretTemplateDataSource{} },
 	}
 }
 
@@ -139,6 +346,8 @@ func (p *TSSProvider) Resources(ctx context.Context) []func() resource.Resource
 		func() resource.Resource {
 			return &TSSSecretDeletionResource{}
 		},
+		func() resource.Resource { return &TSSSecretDeletionsResource{} },
+		func() resource.Resource { return &TSSFolderResource{} },
 		//For the DEBUG environment, uncomment this line to unit test whether the secret value is being fetched successfully.
 		//func() resource.Resource { return &PrintSecretResource{} },
 	}
@@ -0,0 +1,218 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/echoprovider"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAccProtoV6ProviderFactories starts the provider in-process for acceptance tests,
+// which run against a fake Secret Server when TF_ACC is set
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"tss": providerserver.NewProtocol6WithError(New()),
+}
+
+// testAccEchoProviderFactories adds the echo provider, which copies ephemeral values
+// into state so that tests can check them
+var testAccEchoProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"tss":  providerserver.NewProtocol6WithError(New()),
+	"echo": echoprovider.NewProviderServer(),
+}
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     TSSProviderModel
+		wantError string
+	}{
+		{
+			name:  "username and password",
+			model: TSSProviderModel{ServerURL: types.StringValue("https://tss"), Username: types.StringValue("u"), Password: types.StringValue("p")},
+		},
+		{
+			name:  "token",
+			model: TSSProviderModel{ServerURL: types.StringValue("https://tss"), Token: types.StringValue("t")},
+		},
+		{
+			name:  "unknown password",
+			model: TSSProviderModel{ServerURL: types.StringValue("https://tss"), Username: types.StringValue("u"), Password: types.StringUnknown()},
+		},
+		{
+			name:      "missing server URL",
+			model:     TSSProviderModel{Token: types.StringValue("t")},
+			wantError: "Missing Server URL",
+		},
+		{
+			name:      "username and token",
+			model:     TSSProviderModel{ServerURL: types.StringValue("https://tss"), Username: types.StringValue("u"), Password: types.StringValue("p"), Token: types.StringValue("t")},
+			wantError: "Conflicting Credentials",
+		},
+		{
+			name:      "no credentials",
+			model:     TSSProviderModel{ServerURL: types.StringValue("https://tss")},
+			wantError: "Missing Credentials",
+		},
+		{
+			name:      "username without password",
+			model:     TSSProviderModel{ServerURL: types.StringValue("https://tss"), Username: types.StringValue("u")},
+			wantError: "Incomplete Credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := tt.model.validateCredentials()
+			if tt.wantError == "" {
+				if diags.HasError() {
+					t.Fatalf("validateCredentials() = %v, want no errors", diags)
+				}
+				return
+			}
+			if len(diags) != 1 || diags[0].Summary() != tt.wantError {
+				t.Errorf("validateCredentials() = %v, want %q", diags, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestAccProvider_wrongPassword(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "tss" {
+  server_url = %q
+  username   = %q
+  password   = "wrong"
+}
+
+data "tss_secret" "test" {
+  id    = "%d"
+  field = "username"
+}
+`, f.URL, fakeUsername, id),
+				ExpectError: regexp.MustCompile(`failed to get an access token`),
+			},
+		},
+	})
+}
+
+func TestAccProvider_retries(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("web", 0, loginTemplateID, map[string]string{"username": "admin"})
+	config := func(backoff string) string {
+		return fmt.Sprintf(`
+provider "tss" {
+  server_url  = %q
+  username    = %q
+  password    = %q
+  max_retries = 2
+  backoff     = %q
+  jitter      = false
+}
+
+data "tss_secret" "test" {
+  id    = "%d"
+  field = "username"
+}
+`, f.URL, fakeUsername, fakePassword, backoff, id)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config("soon"),
+				ExpectError: regexp.MustCompile(`Invalid Duration`),
+			},
+			{
+				PreConfig: func() {
+					f.failNext(fmt.Sprintf("GET /api/v1/secrets/%d", id), http.StatusServiceUnavailable, http.StatusTooManyRequests)
+				},
+				Config: config("1ms"),
+				Check:  resource.TestCheckResourceAttr("data.tss_secret.test", "value", "admin"),
+			},
+		},
+	})
+}
+
+// newTestProviderServer returns the provider as a protocol server configured for the
+// fake server, for tests that call the protocol directly
+func newTestProviderServer(t *testing.T, f *fakeSecretServer) tfprotov6.ProviderServer {
+	t.Helper()
+
+	providerServer, err := providerserver.NewProtocol6WithError(New())()
+	if err != nil {
+		t.Fatalf("NewProtocol6WithError() error = %v", err)
+	}
+
+	schemas, err := providerServer.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("GetProviderSchema() error = %v", err)
+	}
+
+	resp, err := providerServer.ConfigureProvider(context.Background(), &tfprotov6.ConfigureProviderRequest{
+		Config: testDynamicValue(t, schemas.Provider.ValueType(), map[string]tftypes.Value{
+			"server_url": tftypes.NewValue(tftypes.String, f.URL),
+			"username":   tftypes.NewValue(tftypes.String, fakeUsername),
+			"password":   tftypes.NewValue(tftypes.String, fakePassword),
+		}),
+	})
+	if err != nil || len(resp.Diagnostics) > 0 {
+		t.Fatalf("ConfigureProvider() = %v, %v", resp, err)
+	}
+	return providerServer
+}
+
+// testEphemeralConfig returns the configuration of an ephemeral resource with the given
+// attribute values; all other attributes are null
+func testEphemeralConfig(t *testing.T, providerServer tfprotov6.ProviderServer, typeName string, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	schemas, err := providerServer.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("GetProviderSchema() error = %v", err)
+	}
+	return testDynamicValue(t, schemas.EphemeralResourceSchemas[typeName].ValueType(), values)
+}
+
+// testDynamicValue returns an object of type typ with the given attribute values; all
+// other attributes are null
+func testDynamicValue(t *testing.T, typ tftypes.Type, values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+	t.Helper()
+
+	attributes := map[string]tftypes.Value{}
+	for name, attributeType := range typ.(tftypes.Object).AttributeTypes {
+		attributes[name] = tftypes.NewValue(attributeType, nil)
+		if value, ok := values[name]; ok {
+			attributes[name] = value
+		}
+	}
+
+	value, err := tfprotov6.NewDynamicValue(typ, tftypes.NewValue(typ, attributes))
+	if err != nil {
+		t.Fatalf("NewDynamicValue() error = %v", err)
+	}
+	return &value
+}
+
+// diagnosticSummaries returns the severity and summary of each diagnostic
+func diagnosticSummaries(diags []*tfprotov6.Diagnostic) []string {
+	var summaries []string
+	for _, d := range diags {
+		summaries = append(summaries, fmt.Sprintf("%s: %s", d.Severity, d.Summary))
+	}
+	return summaries
+}
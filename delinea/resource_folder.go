@@ -0,0 +1,295 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64default"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// TSSFolderResource defines the resource implementation
+type TSSFolderResource struct {
+	client *Client // Shared provider client
+}
+
+// Ensure the resource implementation satisfies the import interface
+var _ resource.ResourceWithImportState = &TSSFolderResource{}
+
+// FolderResourceState defines the state structure for the folder resource
+type FolderResourceState struct {
+	ID                  types.Int64  `tfsdk:"id"`
+	Name                types.String `tfsdk:"name"`
+	ParentFolderID      types.Int64  `tfsdk:"parent_folder_id"`
+	InheritPermissions  types.Bool   `tfsdk:"inherit_permissions"`
+	InheritSecretPolicy types.Bool   `tfsdk:"inherit_secret_policy"`
+	SecretPolicyID      types.Int64  `tfsdk:"secret_policy_id"`
+	FolderPath          types.String `tfsdk:"folder_path"`
+}
+
+// Metadata provides the resource type name
+func (r *TSSFolderResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "tss_folder"
+}
+
+// Configure initializes the resource with the provider configuration
+func (r *TSSFolderResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*Client)
+	if !ok {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
+		return
+	}
+
+	// Store the shared client in the resource
+	r.client = client
+}
+
+// Schema defines the schema for the resource
+func (r *TSSFolderResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A folder in Secret Server that secrets can be created in.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.Int64Attribute{
+				Computed:    true,
+				Description: "The ID of the folder.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Required:    true,
+				Description: "The name of the folder.",
+			},
+			"parent_folder_id": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     int64default.StaticInt64(rootFolderID),
+				Description: "The ID of the parent folder. Defaults to -1, which creates a top-level folder.",
+			},
+			"inherit_permissions": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+				Description: "Whether the folder inherits the permissions of its parent folder. Defaults to true.",
+			},
+			"inherit_secret_policy": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(true),
+				Description: "Whether the folder inherits the secret policy of its parent folder. Defaults to true.",
+			},
+			"secret_policy_id": schema.Int64Attribute{
+				Optional:    true,
+				Computed:    true,
+				Description: "The ID of the secret policy of the folder, used when it does not inherit the secret policy.",
+			},
+			"folder_path": schema.StringAttribute{
+				Computed:    true,
+				Description: "The full path of the folder, e.g. '\\Parent\\Child'.",
+			},
+		},
+	}
+}
+
+// Create creates the folder
+func (r *TSSFolderResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx = withLogging(ctx)
+
+	var plan FolderResourceState
+
+	// Read the plan
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	tflog.Debug(ctx, "Creating folder", map[string]interface{}{"name": plan.Name.ValueString()})
+
+	created, err := r.client.CreateFolder(ctx, expandFolder(plan))
+	if err != nil {
+		resp.Diagnostics.AddError("Folder Creation Error", fmt.Sprintf("Failed to create folder: %s", err))
+		return
+	}
+
+	// Read the folder back so that the path and the computed values are set
+	folder, err := r.client.Folder(ctx, created.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Folder Retrieval Error", fmt.Sprintf("Failed to retrieve folder: %s", err))
+		return
+	}
+
+	// Set the state
+	diags = resp.State.Set(ctx, flattenFolder(folder))
+	resp.Diagnostics.Append(diags...)
+}
+
+// Read refreshes the folder from the server
+func (r *TSSFolderResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx = withLogging(ctx)
+
+	var state FolderResourceState
+
+	// Read the state
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	tflog.Debug(ctx, "Reading folder", map[string]interface{}{"folder_id": state.ID.ValueInt64()})
+
+	folder, err := r.client.Folder(ctx, int(state.ID.ValueInt64()))
+	if isNotFound(err) {
+		// The folder was deleted outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Folder Retrieval Error", fmt.Sprintf("Failed to retrieve folder: %s", err))
+		return
+	}
+
+	// Set the state
+	diags = resp.State.Set(ctx, flattenFolder(folder))
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update renames, moves or changes the inheritance settings of the folder
+func (r *TSSFolderResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx = withLogging(ctx)
+
+	var plan FolderResourceState
+	var state FolderResourceState
+
+	// Read the plan and the state
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	diags = req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	folder := expandFolder(plan)
+	folder.ID = int(state.ID.ValueInt64())
+
+	tflog.Debug(ctx, "Updating folder", map[string]interface{}{"folder_id": folder.ID})
+
+	if _, err := r.client.UpdateFolder(ctx, folder); err != nil {
+		resp.Diagnostics.AddError("Folder Update Error", fmt.Sprintf("Failed to update folder: %s", err))
+		return
+	}
+
+	// Refresh state
+	updated, err := r.client.Folder(ctx, folder.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Folder Retrieval Error", fmt.Sprintf("Failed to retrieve folder: %s", err))
+		return
+	}
+
+	// Set the state
+	diags = resp.State.Set(ctx, flattenFolder(updated))
+	resp.Diagnostics.Append(diags...)
+}
+
+// Delete deletes the folder
+func (r *TSSFolderResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx = withLogging(ctx)
+
+	var state FolderResourceState
+
+	// Read the state
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	tflog.Debug(ctx, "Deleting folder", map[string]interface{}{"folder_id": state.ID.ValueInt64()})
+
+	err := r.client.DeleteFolder(ctx, int(state.ID.ValueInt64()))
+	if err != nil && !isNotFound(err) {
+		resp.Diagnostics.AddError("Folder Deletion Error", fmt.Sprintf("Failed to delete folder: %s", err))
+		return
+	}
+}
+
+// ImportState brings an existing folder under Terraform management by its numeric ID
+func (r *TSSFolderResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	folderID, err := strconv.ParseInt(req.ID, 10, 64)
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid Import ID", fmt.Sprintf("The import ID must be the numeric ID of the folder, got '%s'", req.ID))
+		return
+	}
+
+	// Read fills in the rest of the state
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), folderID)...)
+}
+
+// expandFolder converts the Terraform state into a folder for the API
+func expandFolder(state FolderResourceState) Folder {
+	folder := Folder{
+		FolderName:          state.Name.ValueString(),
+		ParentFolderID:      int(state.ParentFolderID.ValueInt64()),
+		InheritPermissions:  state.InheritPermissions.ValueBool(),
+		InheritSecretPolicy: state.InheritSecretPolicy.ValueBool(),
+	}
+	if !state.SecretPolicyID.IsNull() && !state.SecretPolicyID.IsUnknown() {
+		folder.SecretPolicyID = int(state.SecretPolicyID.ValueInt64())
+	}
+	return folder
+}
+
+// flattenFolder converts a folder from the API into Terraform state
+func flattenFolder(folder *Folder) FolderResourceState {
+	state := FolderResourceState{
+		ID:                  types.Int64Value(int64(folder.ID)),
+		Name:                types.StringValue(folder.FolderName),
+		ParentFolderID:      types.Int64Value(int64(folder.ParentFolderID)),
+		InheritPermissions:  types.BoolValue(folder.InheritPermissions),
+		InheritSecretPolicy: types.BoolValue(folder.InheritSecretPolicy),
+		SecretPolicyID:      types.Int64Null(),
+		FolderPath:          types.StringValue(folder.FolderPath),
+	}
+	if folder.SecretPolicyID > 0 {
+		state.SecretPolicyID = types.Int64Value(int64(folder.SecretPolicyID))
+	}
+	return state
+}
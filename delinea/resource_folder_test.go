@@ -0,0 +1,59 @@
+package delinea
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccResourceFolder(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	config := func(name string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_folder" "parent" {
+  name = "Apps"
+}
+
+resource "tss_folder" "test" {
+  name                = %q
+  parent_folder_id    = tss_folder.parent.id
+  inherit_permissions = false
+}
+
+data "tss_folder" "test" {
+  folder_path = tss_folder.test.folder_path
+}
+`, name)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("Web"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_folder.parent", "parent_folder_id", "-1"),
+					resource.TestCheckResourceAttr("tss_folder.test", "folder_path", `\Apps\Web`),
+					resource.TestCheckResourceAttr("tss_folder.test", "inherit_permissions", "false"),
+					resource.TestCheckResourceAttr("tss_folder.test", "inherit_secret_policy", "true"),
+					resource.TestCheckResourceAttrPair("data.tss_folder.test", "id", "tss_folder.test", "id"),
+					resource.TestCheckResourceAttr("data.tss_folder.test", "name", "Web"),
+				),
+			},
+			{
+				Config: config("Api"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_folder.test", "folder_path", `\Apps\Api`),
+					resource.TestCheckResourceAttr("data.tss_folder.test", "name", "Api"),
+				),
+			},
+			{
+				ResourceName:      "tss_folder.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
@@ -7,19 +7,27 @@ import (
 	"strings"
 
 	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 // TSSSecretResource defines the resource implementation
 type TSSSecretResource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
+// Ensure the resource implementation satisfies the import interface
+var _ resource.ResourceWithImportState = &TSSSecretResource{}
+
 // SecretResourceState defines the state structure for the secret resource
 type SecretResourceState struct {
 	ID                               types.Int64   `tfsdk:"id"`
@@ -28,6 +36,7 @@ type SecretResourceState struct {
 	SiteID                           types.String  `tfsdk:"siteid"`
 	SecretTemplateID                 types.String  `tfsdk:"secrettemplateid"`
 	Fields                           []SecretField `tfsdk:"fields"`
+	SensitiveValues                  types.Map     `tfsdk:"sensitive_values"`
 	SshKeyArgs                       *SshKeyArgs   `tfsdk:"sshkeyargs"`
 	Active                           types.Bool    `tfsdk:"active"`
 	SecretPolicyID                   types.Int64   `tfsdk:"secretpolicyid"`
@@ -48,19 +57,27 @@ type SecretResourceState struct {
 }
 
 type SecretField struct {
-	FieldName        types.String `tfsdk:"fieldname"`
-	ItemValue        types.String `tfsdk:"itemvalue"`
-	ItemID           types.Int64  `tfsdk:"itemid"`
-	FieldID          types.Int64  `tfsdk:"fieldid"`
-	FileAttachmentID types.Int64  `tfsdk:"fileattachmentid"`
-	Slug             types.String `tfsdk:"slug"`
-	FieldDescription types.String `tfsdk:"fielddescription"`
-	Filename         types.String `tfsdk:"filename"`
-	IsFile           types.Bool   `tfsdk:"isfile"`
-	IsNotes          types.Bool   `tfsdk:"isnotes"`
-	IsPassword       types.Bool   `tfsdk:"ispassword"`
-	IsList           types.Bool   `tfsdk:"islist"`
-	ListType         types.String `tfsdk:"listtype"`
+	FieldName          types.String `tfsdk:"fieldname"`
+	ItemValue          types.String `tfsdk:"itemvalue"`
+	ItemValueWO        types.String `tfsdk:"itemvalue_wo"`
+	ItemValueWOVersion types.Int64  `tfsdk:"itemvalue_wo_version"`
+	ItemID             types.Int64  `tfsdk:"itemid"`
+	FieldID            types.Int64  `tfsdk:"fieldid"`
+	FileAttachmentID   types.Int64  `tfsdk:"fileattachmentid"`
+	Slug               types.String `tfsdk:"slug"`
+	FieldDescription   types.String `tfsdk:"fielddescription"`
+	Filename           types.String `tfsdk:"filename"`
+	IsFile             types.Bool   `tfsdk:"isfile"`
+	IsNotes            types.Bool   `tfsdk:"isnotes"`
+	IsPassword         types.Bool   `tfsdk:"ispassword"`
+	IsList             types.Bool   `tfsdk:"islist"`
+	ListType           types.String `tfsdk:"listtype"`
+
+	FileContentBase64       types.String `tfsdk:"file_content_base64"`
+	FilePath                types.String `tfsdk:"file_path"`
+	FileHash                types.String `tfsdk:"file_hash"`
+	DownloadFile            types.Bool   `tfsdk:"download_file"`
+	DownloadedContentBase64 types.String `tfsdk:"downloaded_content_base64"`
 }
 
 type SshKeyArgs struct {
@@ -79,20 +96,22 @@ func (r *TSSSecretResource) Configure(ctx context.Context, req resource.Configur
 		return
 	}
 
-	config, ok := req.ProviderData.(*server.Configuration)
+	client, ok := req.ProviderData.(*Client)
 	if !ok {
-		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider configuration")
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
 		return
 	}
 
-	// Store the provider configuration in the resource
-	r.clientConfig = config
+	// Store the shared client in the resource
+	r.client = client
 }
 
 // Create creates the resource
 
 // Create creates the resource
 func (r *TSSSecretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
 	var plan SecretResourceState
 
 	// Read the configuration
@@ -102,41 +121,43 @@ func (r *TSSSecretResource) Create(ctx context.Context, req resource.CreateReque
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
+	// Fill in the write-only values, which are only in the configuration
+	writePlan := plan
+	writePlan.Fields, diags = r.writeValues(ctx, req.Config, plan.Fields, nil, 0)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	// Get the secret data
-	newSecret, err := r.getSecretData(ctx, &plan, client)
+	newSecret, err := r.getSecretData(ctx, &writePlan)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Data Error", fmt.Sprintf("Failed to prepare secret data: %s", err))
 		return
 	}
 
-	fmt.Printf("[DEBUG] creating secret with name %s", newSecret.Name)
+	ctx = maskSecretValues(ctx, newSecret)
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Creating secret", map[string]interface{}{"name": newSecret.Name})
 
 	// Use the client to create the secret
-	createdSecret, err := client.CreateSecret(*newSecret)
+	createdSecret, err := r.client.CreateSecret(ctx, *newSecret)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Creation Error", fmt.Sprintf("Failed to create secret: %s", err))
 		return
 	}
 
-	fmt.Printf("Secret is Created successfully...!")
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Created secret", map[string]interface{}{"secret_id": createdSecret.ID})
 
-	// Refresh state - let Terraform accept the computed values from the server
-	newState, readDiags := r.readSecretByID(ctx, createdSecret.ID, client)
-	resp.Diagnostics.Append(readDiags...)
-	if resp.Diagnostics.HasError() {
+	// Refresh state from the created secret, which the SDK read back from the server
+	newState, err := flattenSecret(ctx, createdSecret)
+	if err != nil {
+		resp.Diagnostics.AddError("State Error", fmt.Sprintf("Failed to flatten secret: %s", err))
 		return
 	}
 
@@ -145,15 +166,28 @@ func (r *TSSSecretResource) Create(ctx context.Context, req resource.CreateReque
 		newState.SshKeyArgs = plan.SshKeyArgs
 	}
 
-	// Preserve file attachment information for file fields
+	// Keep the file inputs and download the attachments that were asked for
+	applyFileInputs(newState, plan.Fields)
+	applyStoredValues(newState, plan.Fields)
+	applySensitiveValues(newState, plan.SensitiveValues)
+
+	// Preserve file attachment information for file fields. A field that uploaded a
+	// file keeps the attachment ID and filename the server assigned to the upload.
 	for i, field := range newState.Fields {
 		if field.IsFile.ValueBool() {
 			// Find the matching field in the plan
 			for _, planField := range plan.Fields {
 				if planField.FieldName.ValueString() == field.FieldName.ValueString() && planField.IsFile.ValueBool() {
-					// Preserve FileAttachmentID and Filename
-					newState.Fields[i].FileAttachmentID = planField.FileAttachmentID
-					newState.Fields[i].Filename = planField.Filename
+					if hasFileInput(planField) {
+						break
+					}
+					// Preserve FileAttachmentID and Filename, unless they are left to the server
+					if !planField.FileAttachmentID.IsUnknown() {
+						newState.Fields[i].FileAttachmentID = planField.FileAttachmentID
+					}
+					if !planField.Filename.IsUnknown() {
+						newState.Fields[i].Filename = planField.Filename
+					}
 					break
 				}
 			}
@@ -167,6 +201,8 @@ func (r *TSSSecretResource) Create(ctx context.Context, req resource.CreateReque
 
 // Update updates the resource
 func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
 	var plan SecretResourceState
 	var state SecretResourceState
 
@@ -179,19 +215,12 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
-		return
-	}
-
 	// Get the secret data
 	// During update, we shouldn't send SSH key generation parameters
 	// because the server doesn't support SSH key generation during update
@@ -208,7 +237,14 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 	// Don't send SSH key args during update - they're only for creation
 	updatePlan.SshKeyArgs = nil
 
-	updatedSecret, err := r.getSecretData(ctx, &updatePlan, client)
+	// Fill in the write-only values and the values that are not kept in state
+	updatePlan.Fields, diags = r.writeValues(ctx, req.Config, plan.Fields, state.Fields, int(state.ID.ValueInt64()))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updatedSecret, err := r.getSecretData(ctx, &updatePlan)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Data Error", fmt.Sprintf("Failed to prepare secret data: %s", err))
 		return
@@ -217,11 +253,20 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 	// If we have SSH key fields, preserve the existing values from the current state
 	for i, field := range updatedSecret.Fields {
 		fieldName := field.FieldName
+		// The fields set through sensitive_values come after those of the fields blocks
+		if i < len(updatePlan.Fields) && hasFileInput(updatePlan.Fields[i]) {
+			continue // The new file replaces the key
+		}
 		if hasSshKeyArgs && (strings.Contains(strings.ToLower(fieldName), "key") ||
 			strings.Contains(strings.ToLower(fieldName), "passphrase")) {
 			// For secrets with SSH keys, preserve the server-generated values
 			for _, stateField := range state.Fields {
 				if strings.EqualFold(stateField.FieldName.ValueString(), fieldName) {
+					// Password values are not in state; writeValues read them from the server
+					if stateField.IsPassword.ValueBool() || usesWriteOnlyValue(stateField) {
+						break
+					}
+
 					// Check if the plan specifically wants to update this field
 					// If not, preserve the existing state value
 					fieldFound := false
@@ -231,10 +276,10 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 							if planField.ItemValue.IsNull() || planField.ItemValue.ValueString() == "" {
 								// Plan is not updating this field, preserve state
 								updatedSecret.Fields[i].ItemValue = stateField.ItemValue.ValueString()
-								fmt.Printf("[DEBUG] Preserving SSH field %s value during update\n", fieldName)
+								tflog.SubsystemTrace(ctx, logResourceSecret, "Preserving SSH field value during update", map[string]interface{}{"field": fieldName})
 							} else {
 								// Plan is updating this field, use new value
-								fmt.Printf("[DEBUG] Updating SSH field %s with new value\n", fieldName)
+								tflog.SubsystemTrace(ctx, logResourceSecret, "Updating SSH field with new value", map[string]interface{}{"field": fieldName})
 							}
 							break
 						}
@@ -243,14 +288,16 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 					if !fieldFound {
 						// Field not found in plan, preserve state value
 						updatedSecret.Fields[i].ItemValue = stateField.ItemValue.ValueString()
-						fmt.Printf("[DEBUG] Preserving SSH field %s value (not in plan)\n", fieldName)
+						tflog.SubsystemTrace(ctx, logResourceSecret, "Preserving SSH field value not in plan", map[string]interface{}{"field": fieldName})
 					}
 
 					// Also preserve the filename for key fields regardless
 					if !stateField.Filename.IsNull() && stateField.Filename.ValueString() != "" {
 						updatedSecret.Fields[i].Filename = stateField.Filename.ValueString()
-						fmt.Printf("[DEBUG] Preserving filename %s for field %s\n",
-							stateField.Filename.ValueString(), fieldName)
+						tflog.SubsystemTrace(ctx, logResourceSecret, "Preserving filename", map[string]interface{}{
+							"field":    fieldName,
+							"filename": stateField.Filename.ValueString(),
+						})
 					}
 					break
 				}
@@ -260,19 +307,20 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 
 	// Update the secret
 	updatedSecret.ID = int(state.ID.ValueInt64())
-	fmt.Printf("[DEBUG] updating secret with id %d", updatedSecret.ID)
-	_, err = client.UpdateSecret(*updatedSecret)
+	ctx = maskSecretValues(ctx, updatedSecret)
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Updating secret", map[string]interface{}{"secret_id": updatedSecret.ID})
+	writtenSecret, err := r.client.UpdateSecret(ctx, *updatedSecret)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Update Error", fmt.Sprintf("Failed to update secret: %s", err))
 		return
 	}
 
-	fmt.Printf("Secret is Updated successfully...!")
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Updated secret", map[string]interface{}{"secret_id": updatedSecret.ID})
 
-	//Refresh state
-	newState, readDiags := r.readSecretByID(ctx, updatedSecret.ID, client)
-	resp.Diagnostics.Append(readDiags...)
-	if resp.Diagnostics.HasError() {
+	// Refresh state from the updated secret, which the SDK read back from the server
+	newState, err := flattenSecret(ctx, writtenSecret)
+	if err != nil {
+		resp.Diagnostics.AddError("State Error", fmt.Sprintf("Failed to flatten secret: %s", err))
 		return
 	}
 
@@ -281,6 +329,11 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 		newState.SshKeyArgs = plan.SshKeyArgs
 	}
 
+	// Keep the file inputs and download the attachments that were asked for
+	applyFileInputs(newState, plan.Fields)
+	applyStoredValues(newState, plan.Fields)
+	applySensitiveValues(newState, plan.SensitiveValues)
+
 	// Preserve file attachment information for file fields and SSH key fields
 	for i, field := range newState.Fields {
 		fieldName := field.FieldName.ValueString()
@@ -289,6 +342,11 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 
 		// Handle both regular file fields and SSH key fields
 		if field.IsFile.ValueBool() || isSSHKeyField {
+			// A field that uploaded a file keeps the attachment ID and filename from the server
+			if planField, ok := findField(plan.Fields, fieldName); ok && hasFileInput(planField) {
+				continue
+			}
+
 			// First check the state (higher priority for existing secrets)
 			for _, stateField := range state.Fields {
 				if stateField.FieldName.ValueString() == fieldName {
@@ -298,8 +356,10 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 					}
 					if !stateField.Filename.IsNull() && stateField.Filename.ValueString() != "" {
 						newState.Fields[i].Filename = stateField.Filename
-						fmt.Printf("[DEBUG] Preserved filename %s for field %s from state\n",
-							stateField.Filename.ValueString(), fieldName)
+						tflog.SubsystemTrace(ctx, logResourceSecret, "Preserved filename from state", map[string]interface{}{
+							"field":    fieldName,
+							"filename": stateField.Filename.ValueString(),
+						})
 					}
 					break
 				}
@@ -311,8 +371,10 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 					if planField.FieldName.ValueString() == fieldName {
 						if !planField.Filename.IsNull() && planField.Filename.ValueString() != "" {
 							newState.Fields[i].Filename = planField.Filename
-							fmt.Printf("[DEBUG] Preserved filename %s for field %s from plan\n",
-								planField.Filename.ValueString(), fieldName)
+							tflog.SubsystemTrace(ctx, logResourceSecret, "Preserved filename from plan", map[string]interface{}{
+								"field":    fieldName,
+								"filename": planField.Filename.ValueString(),
+							})
 						}
 						break
 					}
@@ -328,6 +390,8 @@ func (r *TSSSecretResource) Update(ctx context.Context, req resource.UpdateReque
 
 // Delete deletes the resource
 func (r *TSSSecretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
 	var state SecretResourceState
 
 	// Read the state
@@ -337,29 +401,22 @@ func (r *TSSSecretResource) Delete(ctx context.Context, req resource.DeleteReque
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	fmt.Printf("[DEBUG] deleting secret with id %d", int(state.ID.ValueInt64()))
-
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
-		return
-	}
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Deleting secret", map[string]interface{}{"secret_id": state.ID.ValueInt64()})
 
 	// Delete the secret
-	err = client.DeleteSecret(int(state.ID.ValueInt64()))
-	if err != nil {
+	err := r.client.DeleteSecret(ctx, int(state.ID.ValueInt64()))
+	if err != nil && !isNotFound(err) {
 		resp.Diagnostics.AddError("Secret Deletion Error", fmt.Sprintf("Failed to delete secret: %s", err))
 		return
 	}
 
-	fmt.Printf("Secret is Deleted successfully...!")
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Deleted secret", map[string]interface{}{"secret_id": state.ID.ValueInt64()})
 }
 
 // Schema defines the schema for the resource
@@ -387,6 +444,12 @@ func (r *TSSSecretResource) Schema(ctx context.Context, req resource.SchemaReque
 				Computed:    true,
 				Description: "The ID of the secret policy.",
 			},
+			"sensitive_values": schema.MapAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+				Description: "Values of fields such as passwords, private keys and passphrases, keyed by field slug. They are masked in plan output, and the fields set here are left out of 'fields'.",
+			},
 			"passwordtypewebscriptid": schema.Int64Attribute{
 				Optional:    true,
 				Computed:    true,
@@ -486,6 +549,23 @@ func (r *TSSSecretResource) Schema(ctx context.Context, req resource.SchemaReque
 								stringplanmodifier.UseStateForUnknown(),
 							},
 						},
+						"itemvalue_wo": schema.StringAttribute{
+							Optional:    true,
+							WriteOnly:   true,
+							Sensitive:   true,
+							Description: "The value of the field, sent to Secret Server without being stored in the plan or state. Requires Terraform 1.11 or later. Conflicts with 'itemvalue'.",
+							Validators: []validator.String{
+								stringvalidator.ConflictsWith(path.MatchRelative().AtParent().AtName("itemvalue")),
+								stringvalidator.AlsoRequires(path.MatchRelative().AtParent().AtName("itemvalue_wo_version")),
+							},
+						},
+						"itemvalue_wo_version": schema.Int64Attribute{
+							Optional:    true,
+							Description: "The version of 'itemvalue_wo'. The write-only value is only sent when the field is created or this version changes.",
+							Validators: []validator.Int64{
+								int64validator.AlsoRequires(path.MatchRelative().AtParent().AtName("itemvalue_wo")),
+							},
+						},
 						"itemid": schema.Int64Attribute{
 							Optional: true,
 							Computed: true,
@@ -530,6 +610,45 @@ func (r *TSSSecretResource) Schema(ctx context.Context, req resource.SchemaReque
 							Optional: true,
 							Computed: true,
 						},
+						"file_content_base64": schema.StringAttribute{
+							Optional:    true,
+							Sensitive:   true,
+							Description: "The base64 encoded content to upload as the attachment of a file field. Conflicts with 'file_path' and 'itemvalue'.",
+							Validators: []validator.String{
+								stringvalidator.ConflictsWith(
+									path.MatchRelative().AtParent().AtName("file_path"),
+									path.MatchRelative().AtParent().AtName("itemvalue"),
+								),
+							},
+						},
+						"file_path": schema.StringAttribute{
+							Optional:    true,
+							Description: "The path of a local file to upload as the attachment of a file field. Conflicts with 'file_content_base64' and 'itemvalue'.",
+							Validators: []validator.String{
+								stringvalidator.ConflictsWith(
+									path.MatchRelative().AtParent().AtName("itemvalue"),
+								),
+							},
+						},
+						"file_hash": schema.StringAttribute{
+							Computed:    true,
+							Description: "The SHA-256 hash of the attachment stored in Secret Server, used to detect changes made outside of Terraform.",
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+						},
+						"download_file": schema.BoolAttribute{
+							Optional:    true,
+							Description: "Whether to store the attachment content in 'downloaded_content_base64' when the secret is read.",
+						},
+						"downloaded_content_base64": schema.StringAttribute{
+							Computed:    true,
+							Sensitive:   true,
+							Description: "The base64 encoded content of the attachment. Only set when 'download_file' is true.",
+							PlanModifiers: []planmodifier.String{
+								stringplanmodifier.UseStateForUnknown(),
+							},
+						},
 					},
 				},
 			},
@@ -553,6 +672,8 @@ func (r *TSSSecretResource) Schema(ctx context.Context, req resource.SchemaReque
 }
 
 func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
 	var state SecretResourceState
 
 	// Read the state
@@ -562,25 +683,30 @@ func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest,
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	fmt.Printf("[DEBUG] getting secret with id %d", int(state.ID.ValueInt64()))
+	ctx = maskStateValues(ctx, &state)
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Reading secret", map[string]interface{}{"secret_id": state.ID.ValueInt64()})
 
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
+	// Retrieve the secret
+	secret, err := r.client.Secret(ctx, int(state.ID.ValueInt64()))
+	if isNotFound(err) {
+		// The secret was deleted outside of Terraform
+		resp.State.RemoveResource(ctx)
+		return
+	}
 	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
+		resp.Diagnostics.AddError("Secret Retrieval Error", fmt.Sprintf("Failed to retrieve secret: %s", err))
 		return
 	}
 
-	// Retrieve the secret
-	newState, readDiags := r.readSecretByID(ctx, int(state.ID.ValueInt64()), client)
-	resp.Diagnostics.Append(readDiags...)
-	if resp.Diagnostics.HasError() {
+	newState, err := flattenSecret(ctx, secret)
+	if err != nil {
+		resp.Diagnostics.AddError("State Error", fmt.Sprintf("Failed to flatten secret: %s", err))
 		return
 	}
 
@@ -597,6 +723,11 @@ func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest,
 		hasSshKeyArgs = true
 	}
 
+	// Keep the file inputs and download the attachments that were asked for
+	applyFileInputs(newState, state.Fields)
+	applyStoredValues(newState, state.Fields)
+	applySensitiveValues(newState, state.SensitiveValues)
+
 	// Preserve file attachment information for file fields and SSH key fields
 	for i, field := range newState.Fields {
 		fieldName := field.FieldName.ValueString()
@@ -604,6 +735,11 @@ func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest,
 			strings.Contains(strings.ToLower(fieldName), "passphrase"))
 
 		if field.IsFile.ValueBool() || isSSHKeyField {
+			// A field that uploads a file keeps the attachment ID and filename from the server
+			if oldField, ok := findField(state.Fields, fieldName); ok && hasFileInput(oldField) {
+				continue
+			}
+
 			// Find the matching field in the old state
 			for _, oldField := range state.Fields {
 				if oldField.FieldName.ValueString() == fieldName {
@@ -613,8 +749,10 @@ func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest,
 					}
 					if !oldField.Filename.IsNull() && oldField.Filename.ValueString() != "" {
 						newState.Fields[i].Filename = oldField.Filename
-						fmt.Printf("[DEBUG] Read: Preserved filename %s for field %s\n",
-							oldField.Filename.ValueString(), fieldName)
+						tflog.SubsystemTrace(ctx, logResourceSecret, "Preserved filename from state", map[string]interface{}{
+							"field":    fieldName,
+							"filename": oldField.Filename.ValueString(),
+						})
 					}
 					break
 				}
@@ -627,24 +765,51 @@ func (r *TSSSecretResource) Read(ctx context.Context, req resource.ReadRequest,
 	resp.Diagnostics.Append(diags...)
 }
 
-func (r *TSSSecretResource) readSecretByID(ctx context.Context, id int, client *server.Server) (*SecretResourceState, diag.Diagnostics) {
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
+// ImportState brings an existing secret under Terraform management by its numeric ID
+func (r *TSSSecretResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
+	secretID, err := strconv.Atoi(req.ID)
 	if err != nil {
-		return nil, diag.Diagnostics{
-			diag.NewErrorDiagnostic("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err)),
-		}
+		resp.Diagnostics.AddError("Invalid Import ID", fmt.Sprintf("The import ID must be the numeric ID of the secret, got '%s'", req.ID))
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Importing secret", map[string]interface{}{"secret_id": secretID})
+
+	// Retrieve the secret, including its full field list
+	newState, readDiags := r.readSecretByID(ctx, secretID)
+	resp.Diagnostics.Append(readDiags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	// Retrieve the secret using the provided client
-	secret, err := client.Secret(id)
+	// The server never returns the SSH key generation arguments, so they cannot be
+	// recovered on import. Keys that already exist on the secret are kept as field
+	// values, and filenames are taken from the attachments currently on the server.
+	newState.SshKeyArgs = nil
+
+	// Set the state
+	diags := resp.State.Set(ctx, newState)
+	resp.Diagnostics.Append(diags...)
+}
+
+func (r *TSSSecretResource) readSecretByID(ctx context.Context, id int) (*SecretResourceState, diag.Diagnostics) {
+	// Retrieve the secret using the shared client
+	secret, err := r.client.Secret(ctx, id)
 	if err != nil {
 		return nil, diag.Diagnostics{
 			diag.NewErrorDiagnostic("Secret Retrieval Error", fmt.Sprintf("Failed to retrieve secret: %s", err)),
 		}
 	}
 
-	state, err := flattenSecret(secret)
+	state, err := flattenSecret(ctx, secret)
 	if err != nil {
 		return nil, diag.Diagnostics{
 			diag.NewErrorDiagnostic("State Error", fmt.Sprintf("Failed to flatten secret: %s", err)),
@@ -654,7 +819,7 @@ func (r *TSSSecretResource) readSecretByID(ctx context.Context, id int, client *
 	return state, nil
 }
 
-func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretResourceState, client *server.Server) (*server.Secret, error) {
+func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretResourceState) (*server.Secret, error) {
 	// Convert string attributes to integers
 	folderID, err := stringToInt(state.FolderID)
 	if err != nil {
@@ -670,7 +835,7 @@ func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretReso
 	}
 
 	// Fetch the secret template
-	template, err := client.SecretTemplate(templateID)
+	template, err := r.client.SecretTemplate(ctx, templateID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve secret template: %w", err)
 	}
@@ -678,15 +843,12 @@ func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretReso
 	// Construct the fields dynamically
 	var fields []server.SecretField
 	for _, field := range state.Fields {
-		templateField := server.SecretTemplateField{}
 		fieldName := field.FieldName.ValueString()
 
 		// Match the field name with the template fields
-		for _, record := range template.Fields {
-			if strings.EqualFold(record.Name, fieldName) || strings.EqualFold(record.FieldSlugName, fieldName) {
-				templateField = record
-				break
-			}
+		templateField, ok := templateFieldByName(template, fieldName)
+		if !ok {
+			return nil, fmt.Errorf("the secret template has no field named '%s'", fieldName)
 		}
 
 		// Handle field values appropriately - all optional fields should accept null or empty values
@@ -696,14 +858,14 @@ func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretReso
 		if field.ItemValue.IsNull() {
 			// For null values, use empty string
 			itemValue = ""
-			fmt.Printf("[DEBUG] Field with null value detected: %s, using empty string\n", fieldName)
+			tflog.SubsystemTrace(ctx, logResourceSecret, "Field has a null value, using an empty string", map[string]interface{}{"field": fieldName})
 		} else {
 			// Otherwise use the actual value
 			itemValue = field.ItemValue.ValueString()
 
 			// Log empty strings but keep them as valid values
 			if itemValue == "" {
-				fmt.Printf("[DEBUG] Field with explicit empty string detected: %s\n", fieldName)
+				tflog.SubsystemTrace(ctx, logResourceSecret, "Field is set to an empty string", map[string]interface{}{"field": fieldName})
 			}
 		}
 
@@ -734,9 +896,30 @@ func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretReso
 			secretField.Filename = field.Filename.ValueString()
 		}
 
+		// The SDK uploads the item value of a file field as the attachment content
+		content, ok, diags := fileInput(field)
+		if diags.HasError() {
+			return nil, fmt.Errorf("field '%s': %s", fieldName, diags[0].Detail())
+		}
+		if ok {
+			if !templateField.IsFile {
+				return nil, fmt.Errorf("field '%s' is not a file field and cannot take a file", fieldName)
+			}
+			secretField.ItemValue = string(content)
+			secretField.Filename = fileFilename(field)
+			secretField.FileAttachmentID = 0
+		}
+
 		fields = append(fields, secretField)
 	}
 
+	// Add the fields set through sensitive_values
+	sensitiveFields, err := sensitiveSecretFields(ctx, state.SensitiveValues, template)
+	if err != nil {
+		return nil, err
+	}
+	fields = append(fields, sensitiveFields...)
+
 	// Populate the secret object
 	secret := &server.Secret{
 		Name:             state.Name.ValueString(),
@@ -806,7 +989,7 @@ func (r *TSSSecretResource) getSecretData(ctx context.Context, state *SecretReso
 	return secret, nil
 }
 
-func flattenSecret(secret *server.Secret) (*SecretResourceState, error) {
+func flattenSecret(ctx context.Context, secret *server.Secret) (*SecretResourceState, error) {
 	var fields []SecretField
 
 	for _, f := range secret.Fields {
@@ -817,9 +1000,14 @@ func flattenSecret(secret *server.Secret) (*SecretResourceState, error) {
 		// This ensures Terraform treats empty strings as valid values rather than null
 		itemValue = types.StringValue(f.ItemValue)
 
+		// Passwords are not kept in state; see applyStoredValues
+		if f.IsPassword {
+			itemValue = types.StringValue("")
+		}
+
 		// Add debug logging for empty values
 		if f.ItemValue == "" {
-			fmt.Printf("[DEBUG] Flatten: Field '%s' has empty value\n", f.FieldName)
+			tflog.SubsystemTrace(ctx, logResourceSecret, "Field has an empty value", map[string]interface{}{"field": f.FieldName})
 		}
 
 		field := SecretField{
@@ -842,6 +1030,11 @@ func flattenSecret(secret *server.Secret) (*SecretResourceState, error) {
 			if f.Filename != "" {
 				field.Filename = types.StringValue(f.Filename)
 			}
+
+			// The SDK downloads attachments into the item value
+			if f.FileAttachmentID != 0 && f.Filename != "" {
+				field.FileHash = types.StringValue(fileHash([]byte(f.ItemValue)))
+			}
 		}
 
 		// Special handling for SSH key fields - ensure they have filename if provided by server
@@ -850,7 +1043,10 @@ func flattenSecret(secret *server.Secret) (*SecretResourceState, error) {
 
 		if isSSHKeyField && f.Filename != "" {
 			field.Filename = types.StringValue(f.Filename)
-			fmt.Printf("[DEBUG] Flatten: Found SSH key field %s with filename %s\n", f.FieldName, f.Filename)
+			tflog.SubsystemTrace(ctx, logResourceSecret, "Found SSH key field with filename", map[string]interface{}{
+				"field":    f.FieldName,
+				"filename": f.Filename,
+			})
 		}
 
 		fields = append(fields, field)
@@ -864,6 +1060,7 @@ func flattenSecret(secret *server.Secret) (*SecretResourceState, error) {
 		SecretTemplateID: types.StringValue(strconv.Itoa(secret.SecretTemplateID)),
 		Fields:           fields,
 		Active:           types.BoolValue(secret.Active),
+		SensitiveValues:  types.MapNull(types.StringType),
 	}
 
 	// Handle SSH key args if present
@@ -922,12 +1119,9 @@ func (m sshKeyFieldPlanModifier) MarkdownDescription(ctx context.Context) string
 }
 
 func (m sshKeyFieldPlanModifier) PlanModifyString(ctx context.Context, req planmodifier.StringRequest, resp *planmodifier.StringResponse) {
-	// Log the plan values for debugging
-	fmt.Printf("[DEBUG] PlanModifyString field")
-
 	// If user explicitly set a value (including empty string) in the config, respect it
 	if !req.ConfigValue.IsNull() {
-		fmt.Printf("[DEBUG] Using explicit config value\n")
+		tflog.SubsystemTrace(ctx, logResourceSecret, "Using the configured value", map[string]interface{}{"path": req.Path.String()})
 		resp.PlanValue = req.ConfigValue
 		return
 	}
@@ -935,8 +1129,8 @@ func (m sshKeyFieldPlanModifier) PlanModifyString(ctx context.Context, req planm
 	// For creation with potentially computed values
 	if req.State.Raw.IsNull() && (req.PlanValue.IsNull() || req.PlanValue.ValueString() == "") {
 		// Determine if this value should be computed by SSH key generation
-		if shouldComputeSshKeyValue(req) {
-			fmt.Printf("[DEBUG] Marking value as computed for potential SSH key field\n")
+		if shouldComputeSshKeyValue(ctx, req) {
+			tflog.SubsystemTrace(ctx, logResourceSecret, "Marking value as computed for a potential SSH key field", map[string]interface{}{"path": req.Path.String()})
 			resp.PlanValue = types.StringUnknown()
 			return
 		}
@@ -944,7 +1138,7 @@ func (m sshKeyFieldPlanModifier) PlanModifyString(ctx context.Context, req planm
 
 	// For null values in the plan, convert to empty string for consistency
 	if req.PlanValue.IsNull() {
-		fmt.Printf("[DEBUG] Converting null plan value to empty string\n")
+		tflog.SubsystemTrace(ctx, logResourceSecret, "Converting null plan value to an empty string", map[string]interface{}{"path": req.Path.String()})
 		resp.PlanValue = types.StringValue("")
 		return
 	}
@@ -954,7 +1148,7 @@ func (m sshKeyFieldPlanModifier) PlanModifyString(ctx context.Context, req planm
 }
 
 // Helper function to determine if a field value should be computed by SSH key generation
-func shouldComputeSshKeyValue(req planmodifier.StringRequest) bool {
+func shouldComputeSshKeyValue(ctx context.Context, req planmodifier.StringRequest) bool {
 	// Only mark values as computed during creation for SSH key fields when SSH key generation is enabled
 
 	// Check if this is a create operation (state is null)
@@ -967,7 +1161,7 @@ func shouldComputeSshKeyValue(req planmodifier.StringRequest) bool {
 	// If they did, we should respect that and not compute a value
 	if req.ConfigValue.IsNull() == false && req.ConfigValue.ValueString() == "" {
 		// User explicitly set an empty string, preserve it
-		fmt.Printf("[DEBUG] User explicitly set empty string in config, preserving\n")
+		tflog.SubsystemTrace(ctx, logResourceSecret, "Keeping the empty string set in the configuration", map[string]interface{}{"path": req.Path.String()})
 		return false
 	}
 
@@ -4,15 +4,16 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/DelineaXPM/tss-sdk-go/v2/server"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // TSSSecretDeletionResource defines the resource implementation
 type TSSSecretDeletionResource struct {
-	clientConfig *server.Configuration // Store the provider configuration
+	client *Client // Shared provider client
 }
 
 // SecretDeletionResourceState defines the state structure for the deletion resource
@@ -32,24 +33,28 @@ func (r *TSSSecretDeletionResource) Configure(ctx context.Context, req resource.
 		return
 	}
 
-	config, ok := req.ProviderData.(*server.Configuration)
+	client, ok := req.ProviderData.(*Client)
 	if !ok {
-		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider configuration")
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
 		return
 	}
 
-	// Store the provider configuration in the resource
-	r.clientConfig = config
+	// Store the shared client in the resource
+	r.client = client
 }
 
 // Schema defines the schema for the resource
 func (r *TSSSecretDeletionResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
-		Description: "A resource to delete secrets by ID without requiring them to be in the Terraform state.",
+		Description:        "A resource to delete secrets by ID without requiring them to be in the Terraform state.",
+		DeprecationMessage: "Use tss_secret_deletions instead, which deletes a set of secrets, lists them during plan and deletes IDs added later.",
 		Attributes: map[string]schema.Attribute{
 			"secret_id": schema.Int64Attribute{
 				Required:    true,
-				Description: "The ID of the secret to delete.",
+				Description: "The ID of the secret to delete. Changing it deletes the secret with the new ID.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
 			},
 			"id": schema.StringAttribute{
 				Computed:    true,
@@ -61,6 +66,8 @@ func (r *TSSSecretDeletionResource) Schema(ctx context.Context, req resource.Sch
 
 // Create performs the secret deletion operation
 func (r *TSSSecretDeletionResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx = withLogging(ctx)
+
 	var plan SecretDeletionResourceState
 
 	// Read the plan
@@ -70,30 +77,23 @@ func (r *TSSSecretDeletionResource) Create(ctx context.Context, req resource.Cre
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
-		return
-	}
-
 	secretID := int(plan.SecretID.ValueInt64())
 
 	// Check if the secret exists
-	_, err = client.Secret(secretID)
+	_, err := r.client.Secret(ctx, secretID)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Not Found", fmt.Sprintf("The secret with ID %d does not exist: %s", secretID, err))
 		return
 	}
 
 	// Delete the secret
-	err = client.DeleteSecret(secretID)
+	err = r.client.DeleteSecret(ctx, secretID)
 	if err != nil {
 		resp.Diagnostics.AddError("Secret Deletion Error", fmt.Sprintf("Failed to delete secret with ID %d: %s", secretID, err))
 		return
@@ -109,6 +109,8 @@ func (r *TSSSecretDeletionResource) Create(ctx context.Context, req resource.Cre
 
 // Read checks if the secret still exists
 func (r *TSSSecretDeletionResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx = withLogging(ctx)
+
 	var state SecretDeletionResourceState
 
 	// Read the state
@@ -118,23 +120,16 @@ func (r *TSSSecretDeletionResource) Read(ctx context.Context, req resource.ReadR
 		return
 	}
 
-	// Ensure the client configuration is set
-	if r.clientConfig == nil {
+	// Ensure the client is configured
+	if r.client == nil {
 		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
 		return
 	}
 
-	// Create the server client
-	client, err := server.New(*r.clientConfig)
-	if err != nil {
-		resp.Diagnostics.AddError("Configuration Error", fmt.Sprintf("Failed to create server client: %s", err))
-		return
-	}
-
 	secretID := int(state.SecretID.ValueInt64())
 
 	// Check if the secret still exists
-	_, err = client.Secret(secretID)
+	_, err := r.client.Secret(ctx, secretID)
 	if err != nil {
 		// Secret doesn't exist, which is what we want
 		diags = resp.State.Set(ctx, state)
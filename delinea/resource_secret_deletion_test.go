@@ -0,0 +1,74 @@
+package delinea
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccResourceSecretDeletion(t *testing.T) {
+	f := newFakeSecretServer(t)
+	id := f.addSecret("obsolete", rootFolderID, loginTemplateID, nil)
+	keep := f.addSecret("keep", rootFolderID, loginTemplateID, nil)
+	other := f.addSecret("other", rootFolderID, loginTemplateID, nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+resource "tss_secret_deletion" "test" {
+  secret_id = %d
+}
+`, id),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_secret_deletion.test", "id", fmt.Sprintf("secret_%d", id)),
+					func(s *terraform.State) error {
+						if _, ok := f.secret(id); ok {
+							return fmt.Errorf("secret %d was not deleted", id)
+						}
+						if _, ok := f.secret(keep); !ok {
+							return fmt.Errorf("secret %d was deleted as well", keep)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Changing the ID replaces the resource, which deletes the new secret
+				Config: f.providerConfig() + fmt.Sprintf(`
+resource "tss_secret_deletion" "test" {
+  secret_id = %d
+}
+`, other),
+				Check: func(s *terraform.State) error {
+					if _, ok := f.secret(other); ok {
+						return fmt.Errorf("secret %d was not deleted", other)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccResourceSecretDeletion_missingSecret(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + `
+resource "tss_secret_deletion" "test" {
+  secret_id = 9999
+}
+`,
+				ExpectError: regexp.MustCompile(`The secret with ID 9999 does not exist`),
+			},
+		},
+	})
+}
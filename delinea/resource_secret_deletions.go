@@ -0,0 +1,475 @@
+package delinea
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// TSSSecretDeletionsResource defines the resource implementation
+type TSSSecretDeletionsResource struct {
+	client *Client // Shared provider client
+}
+
+// Ensure the resource implementation plans the deletions
+var _ resource.ResourceWithModifyPlan = &TSSSecretDeletionsResource{}
+
+// SecretDeletionsResourceState defines the state structure for the deletions resource
+type SecretDeletionsResourceState struct {
+	ID                types.String `tfsdk:"id"`
+	IDs               types.Set    `tfsdk:"ids"`
+	DryRun            types.Bool   `tfsdk:"dry_run"`
+	PreventIfInFolder types.List   `tfsdk:"prevent_if_in_folder"`
+	PlannedIDs        types.Set    `tfsdk:"planned_ids"`
+	DeletedIDs        types.Set    `tfsdk:"deleted_ids"`
+}
+
+// Metadata provides the resource type name
+func (r *TSSSecretDeletionsResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = "tss_secret_deletions"
+}
+
+// Configure initializes the resource with the provider configuration
+func (r *TSSSecretDeletionsResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*Client)
+	if !ok {
+		resp.Diagnostics.AddError("Configuration Error", "Failed to retrieve provider client")
+		return
+	}
+
+	// Store the shared client in the resource
+	r.client = client
+}
+
+// Schema defines the schema for the resource
+func (r *TSSSecretDeletionsResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Deletes secrets by ID without requiring them to be in the Terraform state. The secrets to delete are listed during plan, and IDs added later are deleted on update.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "The ID of the resource.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"ids": schema.SetAttribute{
+				ElementType: types.Int64Type,
+				Required:    true,
+				Description: "The IDs of the secrets to delete. Removing an ID does not restore its secret.",
+				Validators: []validator.Set{
+					setvalidator.ValueInt64sAre(int64validator.AtLeast(1)),
+				},
+			},
+			"dry_run": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to only list the secrets that would be deleted in 'planned_ids', without deleting them. Defaults to false.",
+			},
+			"prevent_if_in_folder": schema.ListAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Description: "Folder paths, e.g. '\\Parent\\Child' or 'Parent/Child', whose secrets must not be deleted. Planning fails if a secret to delete is in one of these folders or their subfolders.",
+				Validators: []validator.List{
+					listvalidator.ValueStringsAre(stringvalidator.LengthAtLeast(1)),
+				},
+			},
+			"planned_ids": schema.SetAttribute{
+				ElementType: types.Int64Type,
+				Computed:    true,
+				Description: "The IDs of the secrets that the latest plan deletes, or would delete if 'dry_run' were false.",
+			},
+			"deleted_ids": schema.SetAttribute{
+				ElementType: types.Int64Type,
+				Computed:    true,
+				Description: "The IDs in 'ids' whose secrets were deleted by this resource or did not exist.",
+			},
+		},
+	}
+}
+
+// ModifyPlan lists the secrets that the apply deletes in planned_ids, and fails if one
+// of them is in a protected folder. IDs that were deleted before are not deleted again.
+func (r *TSSSecretDeletionsResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	ctx = withLogging(ctx)
+
+	// Nothing to plan when the resource is being destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan SecretDeletionsResourceState
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The deletions are planned during apply if the IDs or the provider are not known yet
+	if plan.IDs.IsUnknown() || plan.DryRun.IsUnknown() || plan.PreventIfInFolder.IsUnknown() || r.client == nil {
+		return
+	}
+
+	var state *SecretDeletionsResourceState
+	if !req.State.Raw.IsNull() {
+		state = new(SecretDeletionsResourceState)
+		resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	planned, diags := r.planDeletions(ctx, &plan, state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Keep the state when there is nothing to do, so that planned_ids of the last
+	// apply does not show up as a change
+	if state != nil && len(planned) == 0 && !plan.DryRun.ValueBool() &&
+		plan.IDs.Equal(state.IDs) && plan.DryRun.Equal(state.DryRun) && plan.PreventIfInFolder.Equal(state.PreventIfInFolder) {
+		return
+	}
+
+	if plan.DryRun.ValueBool() && len(planned) > 0 {
+		resp.Diagnostics.AddWarning("Dry Run",
+			fmt.Sprintf("The secrets with IDs %s would be deleted if 'dry_run' were false.", formatIDs(planned)))
+	}
+
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, plan)...)
+}
+
+// Create deletes the planned secrets
+func (r *TSSSecretDeletionsResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	ctx = withLogging(ctx)
+
+	var plan SecretDeletionsResourceState
+
+	// Read the plan
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		resp.Diagnostics.AddError("Resource ID Error", fmt.Sprintf("Failed to generate the resource ID: %s", err))
+		return
+	}
+	plan.ID = types.StringValue(hex.EncodeToString(id))
+
+	resp.Diagnostics.Append(r.apply(ctx, &plan, nil)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Read checks that the deleted secrets did not come back. Secrets that exist again
+// are removed from deleted_ids, so that the next apply deletes them again. Deleted
+// secrets are deactivated, so only an active secret counts as restored.
+func (r *TSSSecretDeletionsResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	ctx = withLogging(ctx)
+
+	var state SecretDeletionsResourceState
+
+	// Read the state
+	diags := req.State.Get(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Ensure the client is configured
+	if r.client == nil {
+		resp.Diagnostics.AddError("Client Error", "The server client is not configured")
+		return
+	}
+
+	deleted, diags := int64Set(ctx, state.DeletedIDs)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var restored []int
+	for _, id := range deleted {
+		summary, err := r.client.SecretSummary(ctx, id)
+		if isGone(err) || (err == nil && !summary.Active) {
+			continue
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Secret Retrieval Error", fmt.Sprintf("Failed to check secret %d: %s", id, err))
+			return
+		}
+		restored = append(restored, id)
+	}
+
+	if len(restored) > 0 {
+		resp.Diagnostics.AddWarning("Secrets Still Exist",
+			fmt.Sprintf("The secrets with IDs %s exist even though they were deleted. They will be deleted again on the next apply.", formatIDs(restored)))
+
+		deleted = slices.DeleteFunc(deleted, func(id int) bool { return slices.Contains(restored, id) })
+		state.DeletedIDs = int64SetValue(deleted)
+	}
+
+	diags = resp.State.Set(ctx, state)
+	resp.Diagnostics.Append(diags...)
+}
+
+// Update deletes the secrets of the IDs that were added since the last apply
+func (r *TSSSecretDeletionsResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	ctx = withLogging(ctx)
+
+	var plan, state SecretDeletionsResourceState
+
+	// Read the plan and the state
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.apply(ctx, &plan, &state)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+// Delete only removes the resource from the state, as deleted secrets cannot be restored
+func (r *TSSSecretDeletionsResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+}
+
+// planDeletions sets planned_ids to the IDs whose secrets exist and were not deleted
+// before, and deleted_ids to the IDs that are deleted once the plan is applied. It
+// returns the planned IDs, with errors for the secrets in protected folders.
+func (r *TSSSecretDeletionsResource) planDeletions(ctx context.Context, plan, state *SecretDeletionsResourceState) ([]int, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	ids, d := int64Set(ctx, plan.IDs)
+	diags.Append(d...)
+	guard, d := newFolderGuard(ctx, plan.PreventIfInFolder)
+	diags.Append(d...)
+	var deletedBefore []int
+	if state != nil {
+		deletedBefore, d = int64Set(ctx, state.DeletedIDs)
+		diags.Append(d...)
+	}
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	var planned []int
+	for _, id := range ids {
+		if slices.Contains(deletedBefore, id) {
+			continue
+		}
+
+		exists, d := r.checkDeletion(ctx, id, guard)
+		diags.Append(d...)
+		if exists {
+			planned = append(planned, id)
+		}
+	}
+
+	// Without dry_run every ID is deleted by the apply; with it, only those deleted before
+	deleted := ids
+	if plan.DryRun.ValueBool() {
+		deleted = slices.DeleteFunc(slices.Clone(ids), func(id int) bool {
+			return !slices.Contains(deletedBefore, id)
+		})
+	}
+
+	plan.PlannedIDs = int64SetValue(planned)
+	plan.DeletedIDs = int64SetValue(deleted)
+	return planned, diags
+}
+
+// apply deletes the secrets in planned_ids unless dry_run is set. If a deletion fails,
+// the secrets that were not deleted are left out of deleted_ids.
+func (r *TSSSecretDeletionsResource) apply(ctx context.Context, plan, state *SecretDeletionsResourceState) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// Ensure the client is configured
+	if r.client == nil {
+		diags.AddError("Client Error", "The server client is not configured")
+		return diags
+	}
+
+	// Plan the deletions now if they were not known during plan
+	if plan.PlannedIDs.IsUnknown() || plan.DeletedIDs.IsUnknown() {
+		if _, diags = r.planDeletions(ctx, plan, state); diags.HasError() {
+			return diags
+		}
+	}
+
+	if plan.DryRun.ValueBool() {
+		return diags
+	}
+
+	planned, d := int64Set(ctx, plan.PlannedIDs)
+	diags.Append(d...)
+	guard, d := newFolderGuard(ctx, plan.PreventIfInFolder)
+	diags.Append(d...)
+	deleted, d := int64Set(ctx, plan.DeletedIDs)
+	diags.Append(d...)
+	if diags.HasError() {
+		return diags
+	}
+
+	for i, id := range planned {
+		// Check the folder again, as the secret may have been moved since the plan
+		exists, d := r.checkDeletion(ctx, id, guard)
+		diags.Append(d...)
+
+		if exists && !diags.HasError() {
+			tflog.Debug(ctx, "Deleting secret", map[string]interface{}{"secret_id": id})
+
+			if err := r.client.DeleteSecret(ctx, id); err != nil && !isNotFound(err) {
+				diags.AddError("Secret Deletion Error", fmt.Sprintf("Failed to delete secret with ID %d: %s", id, err))
+			}
+		}
+
+		if diags.HasError() {
+			remaining := planned[i:]
+			deleted = slices.DeleteFunc(deleted, func(id int) bool { return slices.Contains(remaining, id) })
+			plan.DeletedIDs = int64SetValue(deleted)
+			return diags
+		}
+	}
+
+	return diags
+}
+
+// checkDeletion reports whether the secret with the given ID exists and is active,
+// and returns an error if it may not be deleted because of its folder. It reads the
+// summary of the secret, so that the check does not show up as a view in the audit
+// log and works for secrets that require a comment or checkout.
+func (r *TSSSecretDeletionsResource) checkDeletion(ctx context.Context, id int, guard folderGuard) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	summary, err := r.client.SecretSummary(ctx, id)
+	if isNotFound(err) || (err == nil && !summary.Active) {
+		return false, diags
+	}
+	if err != nil {
+		diags.AddAttributeError(path.Root("ids"), "Secret Retrieval Error", fmt.Sprintf("Failed to check secret %d: %s", id, err))
+		return false, diags
+	}
+
+	protected, err := guard.protects(ctx, r.client, summary.FolderID)
+	if err != nil {
+		diags.AddAttributeError(path.Root("ids"), "Folder Retrieval Error", fmt.Sprintf("Failed to retrieve the folder of secret %d: %s", id, err))
+		return true, diags
+	}
+	if protected != "" {
+		diags.AddAttributeError(path.Root("ids"), "Secret In Protected Folder",
+			fmt.Sprintf("The secret with ID %d is in the folder '%s', which is listed in 'prevent_if_in_folder'.", id, protected))
+	}
+	return true, diags
+}
+
+// isGone reports whether err means that a deleted secret cannot be read. Depending on
+// the version and the permissions of the user, Secret Server answers with 404 for a
+// deactivated secret, or with 400 or 403 because access to it is denied. A secret
+// that is restored can be read again, as the user was allowed to delete it.
+func isGone(err error) bool {
+	return isNotFound(err) || hasStatus(err, http.StatusBadRequest) || hasStatus(err, http.StatusForbidden)
+}
+
+// folderGuard holds the normalized folder paths whose secrets must not be deleted, and
+// caches the paths of the folders of the checked secrets
+type folderGuard struct {
+	paths []string
+	cache map[int]string
+}
+
+// newFolderGuard returns a guard for the paths of prevent_if_in_folder
+func newFolderGuard(ctx context.Context, list types.List) (folderGuard, diag.Diagnostics) {
+	guard := folderGuard{cache: map[int]string{}}
+
+	var paths []string
+	diags := list.ElementsAs(ctx, &paths, false)
+	for _, folderPath := range paths {
+		guard.paths = append(guard.paths, strings.ToLower(normalizeFolderPath(folderPath)))
+	}
+	return guard, diags
+}
+
+// protects returns the protected path that contains the given folder or one of its
+// ancestors, or "" if the folder is not protected
+func (g folderGuard) protects(ctx context.Context, c *Client, folderID int) (string, error) {
+	if len(g.paths) == 0 || folderID <= 0 {
+		return "", nil
+	}
+
+	folderPath, ok := g.cache[folderID]
+	if !ok {
+		folder, err := c.Folder(ctx, folderID)
+		if err != nil {
+			return "", err
+		}
+		folderPath = strings.ToLower(normalizeFolderPath(folder.FolderPath))
+		g.cache[folderID] = folderPath
+	}
+
+	for _, protected := range g.paths {
+		if folderPath == protected || strings.HasPrefix(folderPath, protected+folderPathSeparator) {
+			return protected, nil
+		}
+	}
+	return "", nil
+}
+
+// int64Set returns the elements of a set of numbers in ascending order
+func int64Set(ctx context.Context, set types.Set) ([]int, diag.Diagnostics) {
+	var values []int64
+	diags := set.ElementsAs(ctx, &values, false)
+
+	ids := make([]int, len(values))
+	for i, value := range values {
+		ids[i] = int(value)
+	}
+	slices.Sort(ids)
+	return ids, diags
+}
+
+// int64SetValue returns a set of numbers with the given IDs
+func int64SetValue(ids []int) types.Set {
+	elements := make([]attr.Value, len(ids))
+	for i, id := range ids {
+		elements[i] = types.Int64Value(int64(id))
+	}
+	return types.SetValueMust(types.Int64Type, elements)
+}
+
+// formatIDs joins IDs for a diagnostic message
+func formatIDs(ids []int) string {
+	formatted := make([]string, len(ids))
+	for i, id := range ids {
+		formatted[i] = strconv.Itoa(id)
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,139 @@
+package delinea
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccResourceSecretDeletions(t *testing.T) {
+	f := newFakeSecretServer(t)
+	prod := f.addFolder("Prod", rootFolderID)
+	databases := f.addFolder("Databases", prod)
+	first := f.addSecret("obsolete", rootFolderID, loginTemplateID, nil)
+	second := f.addSecret("unused", rootFolderID, loginTemplateID, nil)
+	protected := f.addSecret("db-admin", databases, loginTemplateID, nil)
+
+	config := func(ids []int, extra string) string {
+		list := ""
+		for i, id := range ids {
+			if i > 0 {
+				list += ", "
+			}
+			list += strconv.Itoa(id)
+		}
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_secret_deletions" "test" {
+  ids                  = [%s]
+  prevent_if_in_folder = ["/prod"]
+  %s
+}
+`, list, extra)
+	}
+
+	exists := func(id int, want bool) resource.TestCheckFunc {
+		return func(*terraform.State) error {
+			if _, ok := f.secret(id); ok != want {
+				return fmt.Errorf("secret %d exists = %t, want %t", id, ok, want)
+			}
+			return nil
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      config([]int{first, protected}, ""),
+				ExpectError: regexp.MustCompile(`Secret In Protected Folder`),
+			},
+			{
+				Config: config([]int{first}, "dry_run = true"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "planned_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttr("tss_secret_deletions.test", "planned_ids.*", strconv.Itoa(first)),
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "deleted_ids.#", "0"),
+					exists(first, true),
+				),
+			},
+			{
+				Config: config([]int{first}, ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "deleted_ids.#", "1"),
+					exists(first, false),
+					exists(second, true),
+				),
+			},
+			{
+				// Adding an ID deletes only the new secret
+				Config: config([]int{first, second, 9999}, ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "planned_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttr("tss_secret_deletions.test", "planned_ids.*", strconv.Itoa(second)),
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "deleted_ids.#", "3"),
+					exists(second, false),
+					exists(protected, true),
+					func(*terraform.State) error {
+						if got := f.requestCount(fmt.Sprintf("DELETE /api/v1/secrets/%d", first)); got != 1 {
+							return fmt.Errorf("deletions of secret %d = %d, want 1", first, got)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   config([]int{first, second, 9999}, ""),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceSecretDeletions_inactiveAndCheckout(t *testing.T) {
+	f := newFakeSecretServer(t)
+	inactive := f.addSecret("inactive", rootFolderID, loginTemplateID, nil)
+	f.deactivateSecret(inactive)
+	checkout := f.addSecret("checkout", rootFolderID, loginTemplateID, nil)
+	f.enableCheckout(checkout, 30)
+
+	config := f.providerConfig() + fmt.Sprintf(`
+resource "tss_secret_deletions" "test" {
+  ids = [%d, %d]
+}
+`, inactive, checkout)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// A deactivated secret counts as deleted, and a secret that requires
+				// checkout is checked without viewing it
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "planned_ids.#", "1"),
+					resource.TestCheckTypeSetElemAttr("tss_secret_deletions.test", "planned_ids.*", strconv.Itoa(checkout)),
+					resource.TestCheckResourceAttr("tss_secret_deletions.test", "deleted_ids.#", "2"),
+					func(*terraform.State) error {
+						if _, ok := f.secret(checkout); ok {
+							return fmt.Errorf("secret %d was not deleted", checkout)
+						}
+						for _, id := range []int{inactive, checkout} {
+							if got := f.requestCount(fmt.Sprintf("GET /api/v1/secrets/%d", id)); got != 0 {
+								return fmt.Errorf("views of secret %d = %d, want 0", id, got)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config:   config,
+				PlanOnly: true,
+			},
+		},
+	})
+}
@@ -0,0 +1,529 @@
+package delinea
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+// testAccLoginSecretConfig returns a tss_resource_secret on the login template
+func testAccLoginSecretConfig(f *fakeSecretServer, name, username, password string) string {
+	return f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = %q
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Machine"
+    itemvalue = "web01.example.com"
+  }
+  fields {
+    fieldname = "Username"
+    itemvalue = %q
+  }
+  fields {
+    fieldname = "Password"
+    itemvalue = %q
+  }
+  fields {
+    fieldname = "Notes"
+    itemvalue = ""
+  }
+}
+`, name, loginTemplateID, username, password)
+}
+
+// testCheckSecretField checks a field value of the secret in the fake server whose
+// ID is in the state of the given resource
+func testCheckSecretField(f *fakeSecretServer, resourceName, slug, want string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[resourceName]
+		if !ok {
+			return fmt.Errorf("resource %s not found in state", resourceName)
+		}
+		id, err := strconv.Atoi(rs.Primary.Attributes["id"])
+		if err != nil {
+			return err
+		}
+		got, ok := f.fieldValue(id, slug)
+		if !ok {
+			return fmt.Errorf("secret %d has no field %s", id, slug)
+		}
+		if got != want {
+			return fmt.Errorf("secret %d field %s = %q, want %q", id, slug, got, want)
+		}
+		return nil
+	}
+}
+
+// testCheckNoSecrets checks that the fake server holds no secrets
+func testCheckNoSecrets(f *fakeSecretServer) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if ids := f.secretIDs(); len(ids) > 0 {
+			return fmt.Errorf("secrets %v still exist", ids)
+		}
+		return nil
+	}
+}
+
+func TestAccResourceSecret_basic(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             testCheckNoSecrets(f),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLoginSecretConfig(f, "web", "admin", "s3cret!"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("tss_resource_secret.test", "id"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "name", "web"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.#", "4"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.itemvalue", "admin"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.slug", "username"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.2.ispassword", "true"),
+					testCheckSecretField(f, "tss_resource_secret.test", "username", "admin"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "s3cret!"),
+				),
+			},
+			{
+				Config: testAccLoginSecretConfig(f, "web-renamed", "operator", "n3w-s3cret!"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "name", "web-renamed"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.itemvalue", "operator"),
+					testCheckSecretField(f, "tss_resource_secret.test", "username", "operator"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "n3w-s3cret!"),
+				),
+			},
+			{
+				ResourceName:      "tss_resource_secret.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// Passwords are not read back into state, and import cannot know them
+				ImportStateVerifyIgnore: []string{"fields.2.itemvalue"},
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_driftIsCorrected(t *testing.T) {
+	f := newFakeSecretServer(t)
+	var secretID int
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccLoginSecretConfig(f, "web", "admin", "s3cret!"),
+				Check: func(s *terraform.State) error {
+					id, err := strconv.Atoi(s.RootModule().Resources["tss_resource_secret.test"].Primary.Attributes["id"])
+					secretID = id
+					return err
+				},
+			},
+			{
+				// The username is changed outside of Terraform, so the next plan restores it
+				PreConfig: func() { f.setFieldValue(secretID, "username", "changed") },
+				Config:    testAccLoginSecretConfig(f, "web", "admin", "s3cret!"),
+				Check:     testCheckSecretField(f, "tss_resource_secret.test", "username", "admin"),
+			},
+			{
+				// The secret is deleted outside of Terraform, so it is created again
+				PreConfig: func() { f.deleteSecret(secretID) },
+				Config:    testAccLoginSecretConfig(f, "web", "admin", "s3cret!"),
+				Check:     testCheckSecretField(f, "tss_resource_secret.test", "username", "admin"),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_unknownField(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "web"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Username"
+    itemvalue = "admin"
+  }
+  fields {
+    fieldname = "Hostname"
+    itemvalue = "web01"
+  }
+}
+`, loginTemplateID),
+				ExpectError: regexp.MustCompile(`Unknown Secret Field`),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_writeOnly(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	config := func(password string, version int) string {
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "web"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Machine"
+    itemvalue = "web01.example.com"
+  }
+  fields {
+    fieldname = "Username"
+    itemvalue = "admin"
+  }
+  fields {
+    fieldname            = "Password"
+    itemvalue_wo         = %q
+    itemvalue_wo_version = %d
+  }
+  fields {
+    fieldname = "Notes"
+    itemvalue = ""
+  }
+}
+`, loginTemplateID, password, version)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_11_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("first-password", 1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.2.itemvalue", ""),
+					resource.TestCheckNoResourceAttr("tss_resource_secret.test", "fields.2.itemvalue_wo"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "first-password"),
+				),
+			},
+			{
+				// Without a new version, a changed value is not sent
+				Config: config("ignored-password", 1),
+				Check:  testCheckSecretField(f, "tss_resource_secret.test", "password", "first-password"),
+			},
+			{
+				Config: config("second-password", 2),
+				Check:  testCheckSecretField(f, "tss_resource_secret.test", "password", "second-password"),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_passwordValue(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	config := func(username, password string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "web"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Machine"
+    itemvalue = "web01.example.com"
+  }
+  fields {
+    fieldname = "Username"
+    itemvalue = %q
+  }
+  fields {
+    fieldname = "Password"
+    %s
+  }
+  fields {
+    fieldname = "Notes"
+    itemvalue = ""
+  }
+}
+`, loginTemplateID, username, password)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				// A password set through itemvalue is kept in state
+				Config: config("admin", `itemvalue = "s3cret"`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.2.itemvalue", "s3cret"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "s3cret"),
+				),
+			},
+			{
+				// A password that is no longer set keeps its value on the server
+				Config: config("deploy", ""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					testCheckSecretField(f, "tss_resource_secret.test", "username", "deploy"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "s3cret"),
+				),
+			},
+			{
+				// An empty itemvalue clears it
+				Config: config("deploy", `itemvalue = ""`),
+				Check:  testCheckSecretField(f, "tss_resource_secret.test", "password", ""),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_sensitiveValues(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	config := func(password string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "web"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Machine"
+    itemvalue = "web01.example.com"
+  }
+  fields {
+    fieldname = "Username"
+    itemvalue = "admin"
+  }
+  fields {
+    fieldname = "Notes"
+    itemvalue = ""
+  }
+
+  sensitive_values = {
+    password = %q
+  }
+}
+`, loginTemplateID, password)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("s3cret!"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.#", "3"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "sensitive_values.password", "s3cret!"),
+					testCheckSecretField(f, "tss_resource_secret.test", "password", "s3cret!"),
+				),
+			},
+			{
+				Config: config("n3w-s3cret!"),
+				Check:  testCheckSecretField(f, "tss_resource_secret.test", "password", "n3w-s3cret!"),
+			},
+		},
+	})
+}
+
+// testCheckAttachment checks the attachment of a file field in the fake server, and
+// that the state of the field at the given index holds its attachment ID and filename
+func testCheckAttachment(f *fakeSecretServer, resourceName string, index int, slug, filename, content string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		attributes := s.RootModule().Resources[resourceName].Primary.Attributes
+		id, _ := strconv.Atoi(attributes["id"])
+		file, ok := f.file(id, slug)
+		if !ok || string(file.Content) != content || file.Name != filename {
+			return fmt.Errorf("attachment = %+v, want %s with %q", file, filename, content)
+		}
+
+		field := fmt.Sprintf("fields.%d.", index)
+		if got := attributes[field+"fileattachmentid"]; got != strconv.Itoa(file.ID) {
+			return fmt.Errorf("%sfileattachmentid = %s, want %d", field, got, file.ID)
+		}
+		if got := attributes[field+"filename"]; got != filename {
+			return fmt.Errorf("%sfilename = %s, want %s", field, got, filename)
+		}
+		return nil
+	}
+}
+
+func TestAccResourceSecret_fileAttachment(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	config := func(certificate string) string {
+		return f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "certificate"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Username"
+    itemvalue = "admin"
+  }
+  fields {
+    fieldname           = "Certificate"
+    file_content_base64 = %q
+    filename            = "cert.pem"
+    download_file       = true
+  }
+  fields {
+    fieldname = "Passphrase"
+    itemvalue = "p4ss"
+  }
+}
+`, fileTemplateID, base64.StdEncoding.EncodeToString([]byte(certificate)))
+	}
+
+	checkFile := func(want string) resource.TestCheckFunc {
+		return testCheckAttachment(f, "tss_resource_secret.test", 1, "certificate", "cert.pem", want)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config("first certificate"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					checkFile("first certificate"),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.itemvalue", ""),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.file_hash", fileHash([]byte("first certificate"))),
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.1.downloaded_content_base64",
+						base64.StdEncoding.EncodeToString([]byte("first certificate"))),
+				),
+			},
+			{
+				Config: config("second certificate"),
+				Check:  checkFile("second certificate"),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_fileAttachmentFromPath(t *testing.T) {
+	f := newFakeSecretServer(t)
+	certificate := filepath.Join(t.TempDir(), "server.crt")
+
+	// The filename is not configured, so it is taken from the path
+	config := f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "certificate"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Username"
+    itemvalue = "admin"
+  }
+  fields {
+    fieldname = "Certificate"
+    isfile    = true
+    file_path = %q
+  }
+  fields {
+    fieldname = "Passphrase"
+    itemvalue = "p4ss"
+  }
+}
+`, fileTemplateID, certificate)
+
+	writeCertificate := func(content string) func() {
+		return func() {
+			if err := os.WriteFile(certificate, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: writeCertificate("first certificate"),
+				Config:    config,
+				Check:     testCheckAttachment(f, "tss_resource_secret.test", 1, "certificate", "server.crt", "first certificate"),
+			},
+			{
+				// The new upload gets a new attachment ID
+				PreConfig: writeCertificate("second certificate"),
+				Config:    config,
+				Check:     testCheckAttachment(f, "tss_resource_secret.test", 1, "certificate", "server.crt", "second certificate"),
+			},
+		},
+	})
+}
+
+func TestAccResourceSecret_sshKeyGeneration(t *testing.T) {
+	f := newFakeSecretServer(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: f.providerConfig() + fmt.Sprintf(`
+resource "tss_resource_secret" "test" {
+  name             = "ssh"
+  folderid         = "-1"
+  siteid           = "1"
+  secrettemplateid = "%d"
+
+  fields {
+    fieldname = "Machine"
+    itemvalue = "web01.example.com"
+  }
+  fields {
+    fieldname = "Username"
+    itemvalue = "deploy"
+  }
+  fields {
+    fieldname = "Private Key"
+  }
+  fields {
+    fieldname = "Public Key"
+  }
+  fields {
+    fieldname = "Private Key Passphrase"
+  }
+
+  sshkeyargs {
+    generatepassphrase = true
+    generatesshkeys    = true
+  }
+}
+`, sshTemplateID),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("tss_resource_secret.test", "fields.2.filename", "private_key.pem"),
+					func(s *terraform.State) error {
+						id, _ := strconv.Atoi(s.RootModule().Resources["tss_resource_secret.test"].Primary.Attributes["id"])
+						if _, ok := f.file(id, "private-key"); !ok {
+							return fmt.Errorf("no private key was generated for secret %d", id)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
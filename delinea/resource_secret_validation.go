@@ -0,0 +1,219 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure the resource implementation validates its fields before they reach the server
+var (
+	_ resource.ResourceWithValidateConfig = &TSSSecretResource{}
+	_ resource.ResourceWithModifyPlan     = &TSSSecretResource{}
+)
+
+// ValidateConfig checks the fields blocks for problems that do not need the secret
+// template: missing and duplicate field names
+func (r *TSSSecretResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	fields, diags, ok := configFields(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if !ok {
+		return
+	}
+
+	seen := map[string]int{}
+	for i, field := range fields {
+		fieldPath := path.Root("fields").AtListIndex(i).AtName("fieldname")
+		if field.FieldName.IsUnknown() {
+			continue
+		}
+		if field.FieldName.IsNull() || field.FieldName.ValueString() == "" {
+			resp.Diagnostics.AddAttributeError(fieldPath, "Missing Field Name",
+				"Every fields block must set 'fieldname' to the name or slug of a field of the secret template.")
+			continue
+		}
+
+		key := strings.ToLower(field.FieldName.ValueString())
+		if first, ok := seen[key]; ok {
+			resp.Diagnostics.AddAttributeError(fieldPath, "Duplicate Secret Field",
+				fmt.Sprintf("The field '%s' is already set by fields block %d.", field.FieldName.ValueString(), first))
+			continue
+		}
+		seen[key] = i
+	}
+}
+
+// ModifyPlan checks the fields blocks against the secret template, so that unknown
+// field names, fields that are set twice under their name and slug, and missing
+// required fields are reported during plan instead of by the server during apply
+func (r *TSSSecretResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	ctx = withLogging(ctx, logResourceSecret)
+
+	// Nothing to check when the secret is being destroyed
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	// Plan the hashes of the files to upload and the values that are not kept in state
+	planFileFields(ctx, req, resp)
+	planWriteOnlyFields(ctx, req, resp)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The template can only be fetched once the provider is configured
+	if r.client == nil {
+		return
+	}
+
+	var templateID types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("secrettemplateid"), &templateID)...)
+	if resp.Diagnostics.HasError() || templateID.IsUnknown() || templateID.IsNull() {
+		return
+	}
+
+	fields, diags, ok := configFields(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if !ok {
+		return
+	}
+
+	var sshKeyArgs *SshKeyArgs
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("sshkeyargs"), &sshKeyArgs)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id, err := stringToInt(templateID)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("secrettemplateid"), "Invalid Template ID",
+			fmt.Sprintf("The template ID must be numeric, got '%s'", templateID.ValueString()))
+		return
+	}
+
+	tflog.SubsystemDebug(ctx, logResourceSecret, "Validating fields against secret template", map[string]interface{}{"template_id": id})
+
+	template, err := r.client.SecretTemplate(ctx, id)
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("secrettemplateid"), "Secret Template Error",
+			fmt.Sprintf("Failed to retrieve secret template: %s", err))
+		return
+	}
+
+	// Check every field against the template, remembering which template fields are set
+	allFieldsKnown := true
+	setBy := map[int]int{}
+	for i, field := range fields {
+		if field.FieldName.IsUnknown() {
+			allFieldsKnown = false
+			continue
+		}
+		fieldName := field.FieldName.ValueString()
+		if fieldName == "" {
+			continue // Reported by ValidateConfig
+		}
+
+		fieldPath := path.Root("fields").AtListIndex(i).AtName("fieldname")
+		templateField, ok := templateFieldByName(template, fieldName)
+		if !ok {
+			resp.Diagnostics.AddAttributeError(fieldPath, "Unknown Secret Field",
+				fmt.Sprintf("The secret template '%s' (ID %d) has no field named '%s'. Valid field names are: %s.",
+					template.Name, template.ID, fieldName, templateFieldNames(template)))
+			continue
+		}
+
+		if hasFileInput(field) && !templateField.IsFile {
+			resp.Diagnostics.AddAttributeError(path.Root("fields").AtListIndex(i), "Not a File Field",
+				fmt.Sprintf("The field '%s' is not a file field, so it cannot take file_content_base64 or file_path; use itemvalue instead.", fieldName))
+		}
+
+		if first, ok := setBy[templateField.SecretTemplateFieldID]; ok {
+			// Exact duplicates are already reported by ValidateConfig
+			if !strings.EqualFold(fields[first].FieldName.ValueString(), fieldName) {
+				resp.Diagnostics.AddAttributeError(fieldPath, "Duplicate Secret Field",
+					fmt.Sprintf("The field '%s' is the same template field as '%s' in fields block %d.",
+						fieldName, fields[first].FieldName.ValueString(), first))
+			}
+			continue
+		}
+		setBy[templateField.SecretTemplateFieldID] = i
+	}
+
+	// Check the fields set through sensitive_values
+	if !validateSensitiveValues(ctx, req, resp, template, fields, setBy) {
+		allFieldsKnown = false
+	}
+
+	// A field name that is not known yet may still name a required field
+	if !allFieldsKnown {
+		return
+	}
+
+	// Keys and passphrases are generated by the server when SSH key generation is on
+	generatesKeys := sshKeyArgs != nil &&
+		(sshKeyArgs.GenerateSshKeys.ValueBool() || sshKeyArgs.GeneratePassphrase.ValueBool())
+
+	for _, templateField := range template.Fields {
+		if !templateField.IsRequired {
+			continue
+		}
+		if _, ok := setBy[templateField.SecretTemplateFieldID]; ok {
+			continue
+		}
+		if generatesKeys && isSSHKeyFieldName(templateField.Name) {
+			continue
+		}
+		resp.Diagnostics.AddAttributeError(path.Root("fields"), "Missing Required Secret Field",
+			fmt.Sprintf("The secret template '%s' (ID %d) requires the field '%s' (slug '%s'), but neither a fields block nor sensitive_values sets it.",
+				template.Name, template.ID, templateField.Name, templateField.FieldSlugName))
+	}
+}
+
+// configFields reads the fields blocks from the configuration. It returns false if
+// they cannot be checked yet, e.g. because they come from a dynamic block over
+// values that are only known after apply.
+func configFields(ctx context.Context, config tfsdk.Config) ([]SecretField, diag.Diagnostics, bool) {
+	var list types.List
+	diags := config.GetAttribute(ctx, path.Root("fields"), &list)
+	if diags.HasError() || list.IsUnknown() || list.IsNull() {
+		return nil, diags, false
+	}
+
+	var fields []SecretField
+	diags.Append(list.ElementsAs(ctx, &fields, false)...)
+	return fields, diags, !diags.HasError()
+}
+
+// templateFieldByName finds the template field with the given name or slug, ignoring case
+func templateFieldByName(template *server.SecretTemplate, fieldName string) (server.SecretTemplateField, bool) {
+	for _, record := range template.Fields {
+		if strings.EqualFold(record.Name, fieldName) || strings.EqualFold(record.FieldSlugName, fieldName) {
+			return record, true
+		}
+	}
+	return server.SecretTemplateField{}, false
+}
+
+// templateFieldNames lists the names of the template fields for error messages
+func templateFieldNames(template *server.SecretTemplate) string {
+	names := make([]string, len(template.Fields))
+	for i, record := range template.Fields {
+		names[i] = fmt.Sprintf("'%s'", record.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// isSSHKeyFieldName reports whether the field holds a key or passphrase that SSH key
+// generation fills in
+func isSSHKeyFieldName(fieldName string) bool {
+	return strings.Contains(strings.ToLower(fieldName), "key") ||
+		strings.Contains(strings.ToLower(fieldName), "passphrase")
+}
@@ -0,0 +1,62 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// secretAccess is the justification Secret Server records when a secret is viewed or
+// checked out. Secrets that require a comment or a ticket number cannot be read
+// without it.
+type secretAccess struct {
+	Comment        string `json:"comment,omitempty"`
+	TicketNumber   string `json:"ticketNumber,omitempty"`
+	TicketSystemID int    `json:"ticketSystemId,omitempty"`
+}
+
+// newSecretAccess returns the access justification from the comment, ticket_number
+// and ticket_system_id attributes
+func newSecretAccess(comment, ticketNumber types.String, ticketSystemID types.Int64) secretAccess {
+	return secretAccess{
+		Comment:        comment.ValueString(),
+		TicketNumber:   ticketNumber.ValueString(),
+		TicketSystemID: int(ticketSystemID.ValueInt64()),
+	}
+}
+
+// isSet reports whether any part of the justification is given
+func (a secretAccess) isSet() bool {
+	return a != secretAccess{}
+}
+
+// SecretWithAccess gets the secret with the given ID like Secret, but passes the
+// justification on the view request if one is given. File attachments are downloaded
+// with the same justification.
+func (c *Client) SecretWithAccess(ctx context.Context, id int, access secretAccess) (*server.Secret, error) {
+	if !access.isSet() {
+		return c.Secret(ctx, id)
+	}
+
+	secret := new(server.Secret)
+	if err := c.do(ctx, "POST", fmt.Sprintf("secrets/%d/restricted", id), nil, access, secret); err != nil {
+		return nil, err
+	}
+
+	// Replace the placeholder values of file fields with the attachments, as Secret does
+	for i, field := range secret.Fields {
+		if !field.IsFile || field.FileAttachmentID == 0 || field.Filename == "" {
+			continue
+		}
+
+		var data []byte
+		if err := c.do(ctx, "POST", fmt.Sprintf("secrets/%d/restricted/fields/%s", id, field.Slug), nil, access, &data); err != nil {
+			return nil, err
+		}
+		secret.Fields[i].ItemValue = string(data)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,30 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+)
+
+// secretCheckInArgs is the body of a check-in request
+type secretCheckInArgs struct {
+	ForceCheckIn bool `json:"forceCheckIn"`
+}
+
+// CheckOutSecret checks out the secret with the given ID, so that only the provider's
+// user can read it until it is checked in or the checkout interval ends. The
+// justification is recorded in the secret's audit log.
+func (c *Client) CheckOutSecret(ctx context.Context, id int, access secretAccess) error {
+	return c.do(ctx, "POST", fmt.Sprintf("secrets/%d/check-out", id), nil, access, nil)
+}
+
+// ExtendCheckOut restarts the checkout interval of a secret checked out by the
+// provider's user
+func (c *Client) ExtendCheckOut(ctx context.Context, id int) error {
+	return c.do(ctx, "POST", fmt.Sprintf("secrets/%d/extend-check-out", id), nil, struct{}{}, nil)
+}
+
+// CheckInSecret checks in the secret with the given ID, which must have been checked
+// out by the provider's user
+func (c *Client) CheckInSecret(ctx context.Context, id int) error {
+	return c.do(ctx, "POST", fmt.Sprintf("secrets/%d/check-in", id), nil, secretCheckInArgs{}, nil)
+}
@@ -0,0 +1,178 @@
+package delinea
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// fileInput returns the attachment content configured for a field through
+// file_content_base64 or file_path. ok is false if neither is set or the value is
+// not known yet.
+func fileInput(field SecretField) (content []byte, ok bool, diags diag.Diagnostics) {
+	switch {
+	case !field.FileContentBase64.IsNull() && !field.FileContentBase64.IsUnknown():
+		content, err := base64.StdEncoding.DecodeString(field.FileContentBase64.ValueString())
+		if err != nil {
+			diags.AddError("Invalid File Content", fmt.Sprintf("file_content_base64 is not valid base64: %s", err))
+			return nil, false, diags
+		}
+		return content, true, diags
+
+	case !field.FilePath.IsNull() && !field.FilePath.IsUnknown():
+		content, err := os.ReadFile(field.FilePath.ValueString())
+		if err != nil {
+			diags.AddError("File Read Error", fmt.Sprintf("Failed to read file_path: %s", err))
+			return nil, false, diags
+		}
+		return content, true, diags
+
+	default:
+		return nil, false, diags
+	}
+}
+
+// hasFileInput reports whether the field sets file_content_base64 or file_path
+func hasFileInput(field SecretField) bool {
+	return !field.FileContentBase64.IsNull() || !field.FilePath.IsNull()
+}
+
+// fileHash returns the hex encoded SHA-256 hash of attachment content
+func fileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// fileFilename returns the name to upload an attachment under: the configured
+// filename, or else the base name of file_path
+func fileFilename(field SecretField) string {
+	if !field.Filename.IsNull() && !field.Filename.IsUnknown() && field.Filename.ValueString() != "" {
+		return field.Filename.ValueString()
+	}
+	if !field.FilePath.IsNull() && !field.FilePath.IsUnknown() {
+		return filepath.Base(field.FilePath.ValueString())
+	}
+	return ""
+}
+
+// matchesFieldName reports whether the field has the given name or slug
+func matchesFieldName(field SecretField, fieldName string) bool {
+	return strings.EqualFold(field.FieldName.ValueString(), fieldName) ||
+		strings.EqualFold(field.Slug.ValueString(), fieldName)
+}
+
+// planFileFields sets the planned file_hash of every field that uploads a file to the
+// hash of the configured content, so that an attachment changed outside of Terraform
+// shows up as drift. Such a change uploads the file again, which gets a new attachment
+// ID from the server. It also plans the downloaded content of fields that request it.
+func planFileFields(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	fields, diags, ok := configFields(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if !ok {
+		return
+	}
+
+	var priorFields []SecretField
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("fields"), &priorFields)...)
+	}
+
+	for i, field := range fields {
+		fieldPath := path.Root("fields").AtListIndex(i)
+
+		// Fields without a file input keep the hash and content read from the server
+		downloadedPath := fieldPath.AtName("downloaded_content_base64")
+		if !field.DownloadFile.ValueBool() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, downloadedPath, types.StringNull())...)
+		}
+		if !hasFileInput(field) {
+			continue
+		}
+
+		if field.FileContentBase64.IsUnknown() || field.FilePath.IsUnknown() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, fieldPath.AtName("file_hash"), types.StringUnknown())...)
+			if field.DownloadFile.ValueBool() {
+				resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, downloadedPath, types.StringUnknown())...)
+			}
+			continue
+		}
+
+		content, _, fileDiags := fileInput(field)
+		if fileDiags.HasError() {
+			for _, d := range fileDiags {
+				attribute := "file_path"
+				if !field.FileContentBase64.IsNull() {
+					attribute = "file_content_base64"
+				}
+				resp.Diagnostics.AddAttributeError(fieldPath.AtName(attribute), d.Summary(), d.Detail())
+			}
+			continue
+		}
+		if len(content) == 0 {
+			resp.Diagnostics.AddAttributeError(fieldPath, "Empty File",
+				"The file to upload is empty. Remove file_content_base64 and file_path and set itemvalue to \"\" to remove an attachment.")
+			continue
+		}
+		if !field.FileAttachmentID.IsNull() {
+			resp.Diagnostics.AddAttributeError(fieldPath.AtName("fileattachmentid"), "Conflicting File Attachment ID",
+				"fileattachmentid is assigned by the server to every uploaded file and cannot be set together with file_content_base64 or file_path.")
+			continue
+		}
+		// The server stores a filename without an extension with ".txt" appended
+		if name := field.Filename.ValueString(); name != "" && filepath.Ext(name) == "" {
+			resp.Diagnostics.AddAttributeError(fieldPath.AtName("filename"), "Invalid Filename",
+				fmt.Sprintf("The filename %q has no extension. Add one, such as %q.", name, name+".txt"))
+			continue
+		}
+
+		// The content lives in the file inputs, not in itemvalue
+		hash := fileHash(content)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, fieldPath.AtName("itemvalue"), types.StringValue(""))...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, fieldPath.AtName("file_hash"), types.StringValue(hash))...)
+		if prior, ok := findField(priorFields, field.FieldName.ValueString()); !ok || prior.FileHash.ValueString() != hash {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, fieldPath.AtName("fileattachmentid"), types.Int64Unknown())...)
+			if field.Filename.IsNull() {
+				resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, fieldPath.AtName("filename"), types.StringUnknown())...)
+			}
+		}
+		if field.DownloadFile.ValueBool() {
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, downloadedPath,
+				types.StringValue(base64.StdEncoding.EncodeToString(content)))...)
+		}
+	}
+}
+
+// applyFileInputs copies the file inputs of the configured fields into a state read
+// from the server. Fields that upload a file keep an empty itemvalue, and fields that
+// request it get the attachment content, which the server read put in itemvalue, as base64.
+func applyFileInputs(state *SecretResourceState, inputs []SecretField) {
+	for i, field := range state.Fields {
+		for _, input := range inputs {
+			if !matchesFieldName(field, input.FieldName.ValueString()) {
+				continue
+			}
+
+			state.Fields[i].FileContentBase64 = input.FileContentBase64
+			state.Fields[i].FilePath = input.FilePath
+			state.Fields[i].DownloadFile = input.DownloadFile
+
+			if input.DownloadFile.ValueBool() && field.IsFile.ValueBool() {
+				state.Fields[i].DownloadedContentBase64 = types.StringValue(base64.StdEncoding.EncodeToString([]byte(field.ItemValue.ValueString())))
+			}
+			if hasFileInput(input) {
+				state.Fields[i].ItemValue = types.StringValue("")
+			}
+			break
+		}
+	}
+}
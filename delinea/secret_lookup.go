@@ -0,0 +1,333 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// secretLookup holds the attributes that identify a single secret: its ID, its
+// name with an optional folder path, or a search term
+type secretLookup struct {
+	ID         types.String
+	Name       types.String
+	FolderPath types.String
+	Search     types.String
+	Access     secretAccess // Justification for viewing the secret, if required
+}
+
+// fetch gets the secret identified by the lookup with a single search or read
+func (l secretLookup) fetch(ctx context.Context, c *Client) (*server.Secret, diag.Diagnostics) {
+	secretID, diags := l.resolveID(ctx, c)
+	if diags.HasError() {
+		return nil, diags
+	}
+
+	secret, err := c.SecretWithAccess(ctx, secretID, l.Access)
+	if err != nil {
+		diags.AddError("Secret Fetch Error", fmt.Sprintf("Failed to fetch secret: %s", err))
+	}
+	return secret, diags
+}
+
+// resolveID returns the ID of the secret identified by the lookup without reading
+// the secret, searching for it if it is looked up by name or search term
+func (l secretLookup) resolveID(ctx context.Context, c *Client) (int, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	switch {
+	case !l.Name.IsNull():
+		secretID, err := c.SecretIDByName(ctx, l.Name.ValueString(), l.FolderPath.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("name"), "Secret Lookup Error", err.Error())
+		}
+		return secretID, diags
+
+	case !l.Search.IsNull():
+		secretID, err := c.SecretIDBySearch(ctx, l.Search.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("search"), "Secret Lookup Error", err.Error())
+		}
+		return secretID, diags
+
+	case !l.ID.IsNull() && !l.ID.IsUnknown():
+		secretID, err := strconv.Atoi(l.ID.ValueString())
+		if err != nil {
+			diags.AddError("Invalid Secret ID", "Secret ID must be an integer")
+		}
+		return secretID, diags
+
+	default:
+		diags.AddError("Missing Secret Lookup", "One of 'id', 'name' or 'search' must be set.")
+		return 0, diags
+	}
+}
+
+// Values of on_error, which decides what happens to a secret of tss_secrets that
+// cannot be fetched or does not have the requested field
+const (
+	onErrorFail = "fail" // Fail the read
+	onErrorSkip = "skip" // Leave the secret out of the results
+	onErrorNull = "null" // Keep the secret's position with a null value
+)
+
+// onErrorValues lists the valid values of on_error
+var onErrorValues = []string{onErrorFail, onErrorSkip, onErrorNull}
+
+// secretsLookup holds the attributes that identify a list of secrets: their IDs,
+// their names with an optional folder path, or one search term per secret
+type secretsLookup struct {
+	IDs        []types.Int64
+	Names      []types.String
+	FolderPath types.String
+	Searches   []types.String
+	Access     secretAccess // Justification for viewing the secrets, if required
+}
+
+// fetch gets the secrets identified by the lookup, running the reads or searches
+// concurrently. The results have one entry per ID, name or search term, in order,
+// with exactly one of the secret and the error set.
+func (l secretsLookup) fetch(ctx context.Context, c *Client) ([]*server.Secret, []error) {
+	// resolve returns the ID of the i-th secret
+	var n int
+	var resolve func(i int) (int, error)
+	switch {
+	case l.Names != nil:
+		n = len(l.Names)
+		resolve = func(i int) (int, error) {
+			return c.SecretIDByName(ctx, l.Names[i].ValueString(), l.FolderPath.ValueString())
+		}
+	case l.Searches != nil:
+		n = len(l.Searches)
+		resolve = func(i int) (int, error) {
+			return c.SecretIDBySearch(ctx, l.Searches[i].ValueString())
+		}
+	default:
+		n = len(l.IDs)
+		resolve = func(i int) (int, error) {
+			return int(l.IDs[i].ValueInt64()), nil
+		}
+	}
+
+	secrets := make([]*server.Secret, n)
+	errs := make([]error, n)
+	c.concurrently(n, func(i int) {
+		secretID, err := resolve(i)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		secrets[i], errs[i] = c.SecretWithAccess(ctx, secretID, l.Access)
+	})
+	return secrets, errs
+}
+
+// entry returns the attribute path of the i-th ID, name or search term, and the key
+// of its entry in the errors map: the ID if it is known, otherwise the name or term
+func (l secretsLookup) entry(i int, secret *server.Secret) (path.Path, string) {
+	switch {
+	case l.Names != nil:
+		if secret != nil {
+			return path.Root("names").AtListIndex(i), strconv.Itoa(secret.ID)
+		}
+		return path.Root("names").AtListIndex(i), l.Names[i].ValueString()
+	case l.Searches != nil:
+		if secret != nil {
+			return path.Root("searches").AtListIndex(i), strconv.Itoa(secret.ID)
+		}
+		return path.Root("searches").AtListIndex(i), l.Searches[i].ValueString()
+	default:
+		return path.Root("ids").AtListIndex(i), strconv.FormatInt(l.IDs[i].ValueInt64(), 10)
+	}
+}
+
+// secretsResult holds the computed attributes of tss_secrets
+type secretsResult struct {
+	IDs     []types.Int64
+	Secrets []SecretModel
+	ByID    map[string]types.String
+	Errors  map[string]types.String
+}
+
+// fetchField gets the secrets identified by the lookup and extracts field from each.
+// A secret that cannot be fetched or lacks the field is an error with onError "fail";
+// otherwise it is reported as a warning and in Errors, and is either left out of the
+// results or kept as a null value. An empty onError skips secrets that cannot be
+// fetched and fails on a missing field, with the diagnostics tss_secrets had before
+// on_error existed. ByID only has the secrets that were fetched.
+func (l secretsLookup) fetchField(ctx context.Context, c *Client, field, onError string) (secretsResult, []*server.Secret, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	secrets, errs := l.fetch(ctx, c)
+
+	result := secretsResult{
+		IDs:     []types.Int64{},
+		Secrets: []SecretModel{},
+		ByID:    map[string]types.String{},
+		Errors:  map[string]types.String{},
+	}
+	for i, secret := range secrets {
+		err := errs[i]
+		value, ok := "", false
+		if err == nil {
+			if value, ok = secret.Field(field); !ok {
+				err = fmt.Errorf("the secret does not contain the field '%s'", field)
+			}
+		}
+
+		attributePath, key := l.entry(i, secret)
+		if err != nil {
+			mode := onError
+			if mode == "" {
+				mode = onErrorSkip
+				if errs[i] == nil {
+					mode = onErrorFail
+				}
+			}
+
+			switch {
+			case onError == "" && errs[i] == nil:
+				diags.AddError("Field Not Found", fmt.Sprintf("The secret does not contain the field '%s'", field))
+			case onError == "" && l.IDs != nil:
+				diags.AddWarning("Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret with ID %s: %s", key, err))
+			case onError == "":
+				diags.AddWarning("Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret %s: %s", key, err))
+			case mode == onErrorFail:
+				diags.AddAttributeError(attributePath, "Secret Fetch Error", fmt.Sprintf("Failed to fetch secret %s: %s", key, err))
+			default:
+				diags.AddAttributeWarning(attributePath, "Secret Fetch Warning", fmt.Sprintf("Failed to fetch secret %s: %s", key, err))
+			}
+			result.Errors[key] = types.StringValue(err.Error())
+			if mode != onErrorNull {
+				continue
+			}
+		}
+
+		id := types.Int64Null()
+		switch {
+		case secret != nil:
+			id = types.Int64Value(int64(secret.ID))
+		case l.IDs != nil:
+			id = l.IDs[i]
+		}
+
+		model := SecretModel{ID: id, Value: types.StringNull()}
+		if err == nil {
+			model.Value = types.StringValue(value)
+			result.ByID[key] = model.Value
+		}
+		result.IDs = append(result.IDs, id)
+		result.Secrets = append(result.Secrets, model)
+	}
+
+	return result, secrets, diags
+}
+
+// secretSearchPageSize is the number of search results requested at a time
+const secretSearchPageSize = 100
+
+// SecretIDByName returns the ID of the one secret with the given name without reading
+// it. If folderPath is set, only secrets directly in that folder are considered.
+func (c *Client) SecretIDByName(ctx context.Context, name, folderPath string) (int, error) {
+	folderID := 0
+	if folderPath != "" {
+		folder, err := c.FolderByPath(ctx, folderPath)
+		if err != nil {
+			return 0, err
+		}
+		folderID = folder.ID
+	}
+
+	found, err := c.searchSecrets(ctx, name, folderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for secret '%s': %w", name, err)
+	}
+
+	// The search also matches parts of names and other fields, so narrow it down
+	var matches []server.Secret
+	for _, secret := range found {
+		if !strings.EqualFold(secret.Name, name) {
+			continue
+		}
+		if folderPath != "" && secret.FolderID != folderID {
+			continue
+		}
+		matches = append(matches, secret)
+	}
+
+	description := fmt.Sprintf("named '%s'", name)
+	if folderPath != "" {
+		description += fmt.Sprintf(" in folder '%s'", folderPath)
+	}
+	return singleSecretID(matches, description)
+}
+
+// SecretIDBySearch returns the ID of the one secret that matches the given search
+// term without reading it
+func (c *Client) SecretIDBySearch(ctx context.Context, search string) (int, error) {
+	found, err := c.searchSecrets(ctx, search, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for secrets matching '%s': %w", search, err)
+	}
+
+	return singleSecretID(found, fmt.Sprintf("matching '%s'", search))
+}
+
+// searchSecrets searches the secret names and the Machine, Notes and Username fields
+// the same way as the SDK, but returns the search records of all result pages without
+// reading every match. A folderID other than 0 limits the search to the secrets
+// directly in that folder. Only the ID, name and folder of the records are set.
+// Reading them is left to the caller, as a secret that requires checkout cannot be
+// read before that.
+func (c *Client) searchSecrets(ctx context.Context, searchText string, folderID int) ([]server.Secret, error) {
+	query := url.Values{
+		"paging.filter.searchText":          {searchText},
+		"paging.filter.extendedFields":      {"Machine", "Notes", "Username"},
+		"paging.filter.doNotCalculateTotal": {"true"},
+		"paging.take":                       {strconv.Itoa(secretSearchPageSize)},
+	}
+	if folderID != 0 {
+		query.Set("paging.filter.folderId", strconv.Itoa(folderID))
+	}
+
+	var records []server.Secret
+	for skip := 0; ; skip += secretSearchPageSize {
+		query.Set("paging.skip", strconv.Itoa(skip))
+
+		var result server.SearchResult
+		if err := c.do(ctx, "GET", "secrets", query, nil, &result); err != nil {
+			return nil, err
+		}
+		records = append(records, result.Records...)
+
+		// The total is not calculated, so a short page is the last one
+		if len(result.Records) < secretSearchPageSize {
+			return records, nil
+		}
+	}
+}
+
+// singleSecretID returns the ID of the only secret in matches, or an error that
+// names the matching secret IDs when there is not exactly one
+func singleSecretID(matches []server.Secret, description string) (int, error) {
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("no secret %s was found", description)
+	case 1:
+		return matches[0].ID, nil
+	default:
+		ids := make([]string, len(matches))
+		for i, secret := range matches {
+			ids[i] = fmt.Sprint(secret.ID)
+		}
+		return 0, fmt.Errorf("%d secrets %s were found (IDs %s); use a more specific lookup or the secret ID",
+			len(matches), description, strings.Join(ids, ", "))
+	}
+}
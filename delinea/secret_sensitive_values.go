@@ -0,0 +1,159 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// isSensitiveTemplateField reports whether a template field holds a password, a
+// private key or a passphrase, whose value should not show up in plan output
+func isSensitiveTemplateField(field server.SecretTemplateField) bool {
+	return isSensitiveField(field.IsPassword, field.Name, field.FieldSlugName)
+}
+
+// isSensitiveField reports whether a field with the given names or slugs holds a
+// password, a private key or a passphrase
+func isSensitiveField(isPassword bool, names ...string) bool {
+	if isPassword {
+		return true
+	}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if strings.Contains(name, "private key") || strings.Contains(name, "private-key") ||
+			strings.Contains(name, "passphrase") {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveFieldValues returns the values set through sensitive_values by slug,
+// and the slugs in a stable order
+func sensitiveFieldValues(ctx context.Context, values types.Map) (map[string]string, []string, diag.Diagnostics) {
+	if values.IsNull() || values.IsUnknown() {
+		return nil, nil, nil
+	}
+
+	var byslug map[string]string
+	diags := values.ElementsAs(ctx, &byslug, false)
+
+	slugs := make([]string, 0, len(byslug))
+	for slug := range byslug {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	return byslug, slugs, diags
+}
+
+// sensitiveSecretFields returns the fields to write for the values set through
+// sensitive_values
+func sensitiveSecretFields(ctx context.Context, values types.Map, template *server.SecretTemplate) ([]server.SecretField, error) {
+	byslug, slugs, diags := sensitiveFieldValues(ctx, values)
+	if diags.HasError() {
+		return nil, fmt.Errorf("invalid sensitive_values: %s", diags[0].Detail())
+	}
+
+	fields := make([]server.SecretField, 0, len(slugs))
+	for _, slug := range slugs {
+		templateField, ok := templateFieldByName(template, slug)
+		if !ok {
+			return nil, fmt.Errorf("the secret template has no field with slug '%s'", slug)
+		}
+		fields = append(fields, server.SecretField{
+			FieldDescription: templateField.Description,
+			FieldID:          templateField.SecretTemplateFieldID,
+			FieldName:        templateField.Name,
+			IsFile:           templateField.IsFile,
+			IsNotes:          templateField.IsNotes,
+			IsPassword:       templateField.IsPassword,
+			ItemValue:        byslug[slug],
+			Slug:             templateField.FieldSlugName,
+		})
+	}
+	return fields, nil
+}
+
+// applySensitiveValues removes the fields set through sensitive_values from the
+// fields of a state read from the server and keeps the configured values instead
+func applySensitiveValues(state *SecretResourceState, values types.Map) {
+	state.SensitiveValues = values
+	if values.IsNull() || values.IsUnknown() {
+		return
+	}
+
+	fields := state.Fields[:0]
+	for _, field := range state.Fields {
+		managed := false
+		for slug := range values.Elements() {
+			if matchesFieldName(field, slug) {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			fields = append(fields, field)
+		}
+	}
+	state.Fields = fields
+}
+
+// validateSensitiveValues checks the keys of sensitive_values against the template
+// and warns about fields blocks that put a password, private key or passphrase in
+// the plain itemvalue. setBy maps the template field IDs to the fields blocks that set
+// them, and the fields set here are added to it. It returns false if the keys are not
+// known yet.
+func validateSensitiveValues(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+	template *server.SecretTemplate, fields []SecretField, setBy map[int]int) bool {
+	var values types.Map
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("sensitive_values"), &values)...)
+	if resp.Diagnostics.HasError() || values.IsUnknown() {
+		return false
+	}
+
+	slugs := make([]string, 0, len(values.Elements()))
+	for slug := range values.Elements() {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+
+	for _, slug := range slugs {
+		keyPath := path.Root("sensitive_values").AtMapKey(slug)
+		templateField, ok := templateFieldByName(template, slug)
+		if !ok {
+			resp.Diagnostics.AddAttributeError(keyPath, "Unknown Secret Field",
+				fmt.Sprintf("The secret template '%s' (ID %d) has no field with slug '%s'.", template.Name, template.ID, slug))
+			continue
+		}
+		if i, ok := setBy[templateField.SecretTemplateFieldID]; ok {
+			setElsewhere := fmt.Sprintf("fields block %d", i)
+			if i < 0 {
+				setElsewhere = "another key of sensitive_values"
+			}
+			resp.Diagnostics.AddAttributeError(keyPath, "Duplicate Secret Field",
+				fmt.Sprintf("The field '%s' is also set by %s.", slug, setElsewhere))
+			continue
+		}
+		setBy[templateField.SecretTemplateFieldID] = -1
+	}
+
+	for i, field := range fields {
+		if field.ItemValue.IsNull() || field.FieldName.IsUnknown() {
+			continue
+		}
+		templateField, ok := templateFieldByName(template, field.FieldName.ValueString())
+		if ok && isSensitiveTemplateField(templateField) {
+			resp.Diagnostics.AddAttributeWarning(path.Root("fields").AtListIndex(i).AtName("itemvalue"), "Sensitive Value Not Masked",
+				fmt.Sprintf("The field '%s' holds a secret value, but itemvalue is shown in plan output. "+
+					"Set it with sensitive_values[\"%s\"] or itemvalue_wo instead.", field.FieldName.ValueString(), templateField.FieldSlugName))
+		}
+	}
+	return true
+}
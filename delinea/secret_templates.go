@@ -0,0 +1,58 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+)
+
+// secretTemplateSummary is a secret template as returned by the template search
+type secretTemplateSummary struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+}
+
+// secretTemplateSearchResult is a page of templates returned by the template search
+type secretTemplateSearchResult struct {
+	Records []secretTemplateSummary `json:"records"`
+}
+
+// SecretTemplateByName gets the one secret template with the given name, ignoring case
+func (c *Client) SecretTemplateByName(ctx context.Context, name string) (*server.SecretTemplate, error) {
+	query := url.Values{
+		"filter.searchText":      {name},
+		"filter.includeInactive": {"true"},
+		"take":                   {"1000"},
+	}
+
+	var result secretTemplateSearchResult
+	if err := c.do(ctx, "GET", "secret-templates", query, nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to search secret templates: %w", err)
+	}
+
+	// The search also matches parts of names, so narrow it down
+	var matches []int
+	for _, template := range result.Records {
+		if strings.EqualFold(template.Name, name) {
+			matches = append(matches, template.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no secret template named '%s' was found", name)
+	case 1:
+		return c.SecretTemplate(ctx, matches[0])
+	default:
+		ids := make([]string, len(matches))
+		for i, id := range matches {
+			ids[i] = fmt.Sprint(id)
+		}
+		return nil, fmt.Errorf("%d secret templates named '%s' were found (IDs %s); use the template ID",
+			len(matches), name, strings.Join(ids, ", "))
+	}
+}
@@ -0,0 +1,122 @@
+package delinea
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/DelineaXPM/tss-sdk-go/v2/server"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// usesWriteOnlyValue reports whether the field value is set through itemvalue_wo.
+// The write-only value itself is never in the plan or state, so its version is checked.
+func usesWriteOnlyValue(field SecretField) bool {
+	return !field.ItemValueWOVersion.IsNull()
+}
+
+// findField returns the field with the given name or slug
+func findField(fields []SecretField, fieldName string) (SecretField, bool) {
+	for _, field := range fields {
+		if matchesFieldName(field, fieldName) {
+			return field, true
+		}
+	}
+	return SecretField{}, false
+}
+
+// planWriteOnlyFields plans an empty itemvalue for the fields set through
+// itemvalue_wo, as their values are not kept in state
+func planWriteOnlyFields(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	fields, diags, ok := configFields(ctx, req.Config)
+	resp.Diagnostics.Append(diags...)
+	if !ok {
+		return
+	}
+
+	for i, field := range fields {
+		if usesWriteOnlyValue(field) {
+			itemValuePath := path.Root("fields").AtListIndex(i).AtName("itemvalue")
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, itemValuePath, types.StringValue(""))...)
+		}
+	}
+}
+
+// writeValues returns a copy of the planned fields with the values that are not kept
+// in state filled in. A field set through itemvalue_wo takes the write-only value from
+// the configuration when it is new or its version changed. Otherwise, on update, a
+// write-only or password field that the configuration does not set keeps its current
+// value on the server; a null itemvalue does not clear a password, only "" does.
+func (r *TSSSecretResource) writeValues(ctx context.Context, config tfsdk.Config, planned, prior []SecretField, secretID int) ([]SecretField, diag.Diagnostics) {
+	configured, diags, ok := configFields(ctx, config)
+	if !ok {
+		return planned, diags
+	}
+
+	fields := append([]SecretField(nil), planned...)
+	var current *server.Secret
+	for i, field := range fields {
+		if i >= len(configured) {
+			break
+		}
+		fieldName := field.FieldName.ValueString()
+		priorField, exists := findField(prior, fieldName)
+
+		keepCurrent := false
+		switch {
+		case usesWriteOnlyValue(configured[i]):
+			if exists && priorField.ItemValueWOVersion.Equal(field.ItemValueWOVersion) {
+				keepCurrent = true
+			} else {
+				fields[i].ItemValue = configured[i].ItemValueWO
+			}
+		case exists && priorField.IsPassword.ValueBool() && configured[i].ItemValue.IsNull():
+			keepCurrent = true
+		}
+		if !keepCurrent || !exists {
+			continue
+		}
+
+		// Read the current values once, and only when one is needed
+		if current == nil {
+			secret, err := r.client.Secret(ctx, secretID)
+			if err != nil {
+				diags.AddError("Secret Retrieval Error", fmt.Sprintf("Failed to retrieve the current field values: %s", err))
+				return nil, diags
+			}
+			current = secret
+		}
+		for _, currentField := range current.Fields {
+			if strings.EqualFold(currentField.FieldName, fieldName) || strings.EqualFold(currentField.Slug, fieldName) {
+				fields[i].ItemValue = types.StringValue(currentField.ItemValue)
+				break
+			}
+		}
+	}
+	return fields, diags
+}
+
+// applyStoredValues decides which field values a state read from the server keeps.
+// flattenSecret leaves out the values of password fields; a password set through
+// itemvalue is put back from the configured fields, as Terraform requires configured
+// values in state, and fields set through itemvalue_wo keep an empty value and their version.
+func applyStoredValues(state *SecretResourceState, inputs []SecretField) {
+	for i, field := range state.Fields {
+		input, ok := findField(inputs, field.FieldName.ValueString())
+		if !ok {
+			continue
+		}
+
+		state.Fields[i].ItemValueWOVersion = input.ItemValueWOVersion
+		switch {
+		case usesWriteOnlyValue(input):
+			state.Fields[i].ItemValue = types.StringValue("")
+		case field.IsPassword.ValueBool() && !input.ItemValue.IsNull() && !input.ItemValue.IsUnknown():
+			state.Fields[i].ItemValue = input.ItemValue
+		}
+	}
+}
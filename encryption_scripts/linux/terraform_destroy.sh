@@ -4,10 +4,19 @@
 export STATE_FILE="terraform.tfstate"
 export STATE_BACKUP_FILE="terraform.tfstate.backup"
 export LOCK_FILE="lockfile.lock"
-export TFSTATE_PASSPHRASE="${TF_VAR_tss_username}${TF_VAR_tss_password}"
+if [ -n "$TFSTATE_KEY_SECRET_ID" ]; then
+    # Read the state encryption key from Secret Server with the provider credentials
+    export TSS_SERVER_URL="${TSS_SERVER_URL:-$TF_VAR_tss_server_url}"
+    if [ -z "$TSS_TOKEN" ]; then
+        export TSS_USERNAME="${TSS_USERNAME:-$TF_VAR_tss_username}"
+        export TSS_PASSWORD="${TSS_PASSWORD:-$TF_VAR_tss_password}"
+    fi
+else
+    export TFSTATE_PASSPHRASE="${TFSTATE_PASSPHRASE:-${TF_VAR_tss_username}${TF_VAR_tss_password}}"
+fi
 
 # Check if TFSTATE_PASSPHRASE is set
-if [ -z "$TFSTATE_PASSPHRASE" ]; then
+if [ -z "$TFSTATE_KEY_SECRET_ID" ] && [ -z "$TFSTATE_PASSPHRASE" ]; then
     echo "Username and Password are not set in environment variable"
     exit 1
 fi
@@ -5,7 +5,12 @@ REM Set paths for the encrypted and decrypted state files
 set STATE_FILE=terraform.tfstate
 set STATE_BACKUP_FILE=terraform.tfstate.backup
 set LOCK_FILE=lockfile.lock
-set TFSTATE_PASSPHRASE=%TF_VAR_tss_username%%TF_VAR_tss_password%
+if defined TFSTATE_KEY_SECRET_ID (
+    REM Read the state encryption key from Secret Server with the provider credentials
+    if not defined TSS_SERVER_URL set TSS_SERVER_URL=%TF_VAR_tss_server_url%
+    if not defined TSS_TOKEN if not defined TSS_USERNAME set TSS_USERNAME=%TF_VAR_tss_username%
+    if not defined TSS_TOKEN if not defined TSS_PASSWORD set TSS_PASSWORD=%TF_VAR_tss_password%
+) else if not defined TFSTATE_PASSPHRASE set TFSTATE_PASSPHRASE=%TF_VAR_tss_username%%TF_VAR_tss_password%
 
 for /r %%i in (terraform-provider-tss*.exe) do @if exist "%%i" set "TF_PLUGIN_PATH=%%~fi"
 
@@ -15,7 +20,7 @@ if "%TF_PLUGIN_PATH%"=="" (
 	exit /b 1
 )
 
-if "%TFSTATE_PASSPHRASE%"=="" (
+if not defined TFSTATE_KEY_SECRET_ID if "%TFSTATE_PASSPHRASE%"=="" (
     echo Username and Password are not set in environment variable
 	exit /b 1
 )
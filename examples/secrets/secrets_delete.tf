@@ -30,7 +30,6 @@ provider "tss" {
   server_url = var.tss_server_url
 }
 
-resource "tss_secret_deletion" "delete_secrets" {
-  for_each = toset(var.tss_secret_ids)
-  secret_id = tonumber(each.key)
+resource "tss_secret_deletions" "delete_secrets" {
+  ids = [for id in var.tss_secret_ids : tonumber(id)]
 }